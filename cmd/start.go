@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -11,11 +12,14 @@ import (
 	"github.com/xo/dburl"
 
 	"github.com/imle/discord-bot-event-channels/cmd/start"
+	"github.com/imle/discord-bot-event-channels/pkg/bot"
 )
 
 var (
-	token       = ""
-	dburlString = ""
+	token             = ""
+	dburlString       = ""
+	interactionSecret = os.Getenv("INTERACTION_SECRET")
+	devGuildID        = ""
 )
 
 var startCmd = &cobra.Command{
@@ -36,6 +40,9 @@ var startCmd = &cobra.Command{
 		manager, err := start.InitializeEventManager(logger, start.EngineConfig{
 			URI:        u,
 			LogQueries: true,
+		}, bot.EventManagerConfig{
+			InteractionSecret: interactionSecret,
+			DevGuildID:        devGuildID,
 		})
 		if err != nil {
 			return err
@@ -70,6 +77,10 @@ var startCmd = &cobra.Command{
 			}
 		}()
 
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go manager.RunBackgroundTasks(ctx, session)
+
 		stop := make(chan os.Signal, 1)
 		defer close(stop)
 		signal.Notify(stop, os.Interrupt)
@@ -85,4 +96,6 @@ func init() {
 
 	startCmd.Flags().StringVarP(&token, "token", "t", token, "bot token")
 	startCmd.Flags().StringVar(&dburlString, "dburl", dburlString, "dburl connection string")
+	startCmd.Flags().StringVar(&interactionSecret, "interaction-secret", interactionSecret, "secret used to sign component ids, defaults to $INTERACTION_SECRET")
+	startCmd.Flags().StringVar(&devGuildID, "dev-guild", devGuildID, "register commands to this guild only instead of globally")
 }
@@ -10,7 +10,7 @@ import (
 	"github.com/imle/discord-bot-event-channels/pkg/bot"
 )
 
-func InitializeEventManager(_ *logrus.Logger, _ EngineConfig) (*bot.EventManager, error) {
+func InitializeEventManager(_ *logrus.Logger, _ EngineConfig, _ bot.EventManagerConfig) (*bot.EventManager, error) {
 	wire.Build(
 		NewEngine,
 		bot.NewEventManager,
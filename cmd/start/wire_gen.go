@@ -18,12 +18,12 @@ import (
 
 // Injectors from wire.go:
 
-func InitializeEventManager(logger *logrus.Logger, engineConfig EngineConfig) (*bot.EventManager, error) {
+func InitializeEventManager(logger *logrus.Logger, engineConfig EngineConfig, eventManagerConfig bot.EventManagerConfig) (*bot.EventManager, error) {
 	engineInterface, err := NewEngine(engineConfig, logger)
 	if err != nil {
 		return nil, err
 	}
-	eventManager := bot.NewEventManager(logger, engineInterface)
+	eventManager := bot.NewEventManager(logger, engineInterface, eventManagerConfig)
 	return eventManager, nil
 }
 
@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// ttlCache holds values per key for a short time so bursts of requests, such as autocomplete keystrokes, share
+// a single Discord API call.
+type ttlCache[T any] struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]ttlCacheEntry[T]
+	lastSweep time.Time
+}
+
+type ttlCacheEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+func newTTLCache[T any](ttl time.Duration) *ttlCache[T] {
+	return &ttlCache[T]{
+		ttl:     ttl,
+		entries: map[string]ttlCacheEntry[T]{},
+	}
+}
+
+// Returns the cached value for key, calling fetch to fill the cache when it is missing or expired.
+func (c *ttlCache[T]) get(key string, fetch func() (T, error)) (T, error) {
+	c.mu.Lock()
+	entry, has := c.entries[key]
+	c.mu.Unlock()
+
+	if has && time.Now().Before(entry.expiresAt) {
+		return entry.value, nil
+	}
+
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	c.sweep(now)
+	c.entries[key] = ttlCacheEntry[T]{
+		value:     value,
+		expiresAt: now.Add(c.ttl),
+	}
+	c.mu.Unlock()
+
+	return value, nil
+}
+
+// Drops the expired entries, at most once per ttl, so keys that are never asked for again don't pile up. The
+// caller holds the lock.
+func (c *ttlCache[T]) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	c.lastSweep = now
+}
+
+// Stores the value for key until the ttl passed.
+func (c *ttlCache[T]) set(key string, value T) {
+	c.mu.Lock()
+	now := time.Now()
+	c.sweep(now)
+	c.entries[key] = ttlCacheEntry[T]{
+		value:     value,
+		expiresAt: now.Add(c.ttl),
+	}
+	c.mu.Unlock()
+}
+
+// Returns the value for key when it is cached and not expired, without fetching it.
+func (c *ttlCache[T]) peek(key string) (T, bool) {
+	c.mu.Lock()
+	entry, has := c.entries[key]
+	c.mu.Unlock()
+
+	if !has || !time.Now().Before(entry.expiresAt) {
+		var zero T
+		return zero, false
+	}
+
+	return entry.value, true
+}
+
+func (c *ttlCache[T]) invalidate(key string) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
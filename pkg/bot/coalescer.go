@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// coalescer delays work per key and only runs the last one scheduled, so a storm of updates for the same key
+// turns into a single call once it settles.
+type coalescer struct {
+	mu     sync.Mutex
+	delay  time.Duration
+	timers map[string]*time.Timer
+}
+
+func newCoalescer(delay time.Duration) *coalescer {
+	return &coalescer{
+		delay:  delay,
+		timers: map[string]*time.Timer{},
+	}
+}
+
+// Runs fn once nothing else was scheduled for key during the delay, replacing whatever was pending for it.
+func (c *coalescer) schedule(key string, fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if timer, has := c.timers[key]; has {
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(c.delay, func() {
+		c.mu.Lock()
+		if c.timers[key] == timer {
+			delete(c.timers, key)
+		}
+		c.mu.Unlock()
+
+		fn()
+	})
+	c.timers[key] = timer
+}
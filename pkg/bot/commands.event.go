@@ -1,14 +1,11 @@
 package bot
 
 import (
+	"strings"
+
 	"github.com/bwmarrin/discordgo"
 )
 
-var globalCommands = []*discordgo.ApplicationCommand{
-	&cmdOptions,
-	&cmdSync,
-}
-
 var dmPermission = false
 var defaultMemberPermissions int64 = discordgo.PermissionManageServer
 
@@ -19,13 +16,14 @@ const (
 	ConfigOptionAnnounceChannel            ConfigOption = "announce-channel"
 	ConfigOptionDeleteChannelWhenEventDone ConfigOption = "delete-channel-when-event-done"
 	ConfigOptionCategoryID                 ConfigOption = "category-channel"
+	ConfigOptionChannelTopic               ConfigOption = "channel-topic"
+	ConfigOptionVoiceAccess                ConfigOption = "voice-access"
 )
 
-var cmdOptions = discordgo.ApplicationCommand{
-	Name:                     "event-channels-bot-options",
-	Description:              "Set bot options",
-	DMPermission:             &dmPermission,
-	DefaultMemberPermissions: &defaultMemberPermissions,
+var cmdConfigSet = discordgo.ApplicationCommandOption{
+	Name:        SubcommandSet,
+	Description: "Set bot options",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
 	Options: []*discordgo.ApplicationCommandOption{
 		{
 			Name:        ConfigOptionAnnounceMessage,
@@ -50,6 +48,17 @@ var cmdOptions = discordgo.ApplicationCommand{
 			Type:         discordgo.ApplicationCommandOptionChannel,
 			ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildCategory},
 		},
+		{
+			Name:        ConfigOptionChannelTopic,
+			Description: "Topic of event channels using %EVENT%, %START%, %LOCATION% and %DESCRIPTION%, \\n for new lines",
+			Type:        discordgo.ApplicationCommandOptionString,
+			MaxLength:   maxChannelTopicLength,
+		},
+		{
+			Name:        ConfigOptionVoiceAccess,
+			Description: "Whether to let interested users into private voice and stage channels of events",
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+		},
 	},
 }
 
@@ -58,6 +67,8 @@ func getConfigOptionsMap(s *discordgo.Session, options map[string]*discordgo.App
 	channel := options[ConfigOptionAnnounceChannel]
 	shouldDelete := options[ConfigOptionDeleteChannelWhenEventDone]
 	category := options[ConfigOptionCategoryID]
+	topic := options[ConfigOptionChannelTopic]
+	voiceAccess := options[ConfigOptionVoiceAccess]
 
 	if message != nil {
 		g.NewEventChannelMessage = message.StringValue()
@@ -83,13 +94,351 @@ func getConfigOptionsMap(s *discordgo.Session, options map[string]*discordgo.App
 		g.EventChannelParentID = channelValue.ID
 	}
 
+	if topic != nil {
+		g.ChannelTopicTemplate = strings.Replace(topic.StringValue(), `\n`, "\n", -1)
+	}
+
+	if voiceAccess != nil {
+		g.VoiceAccess = voiceAccess.BoolValue()
+	}
+
 	return ""
 }
 
-var cmdSync = discordgo.ApplicationCommand{
-	Name:                     "event-channels-sync",
-	Description:              "Run the initial sync",
+type CommandOption = string
+
+const (
+	CommandOptionEvent          CommandOption = "event"
+	CommandOptionChannel        CommandOption = "channel"
+	CommandOptionReleaseChannel CommandOption = "release-old-channel"
+	CommandOptionPage           CommandOption = "page"
+	CommandOptionTrackedChannel CommandOption = "tracked-channel"
+	CommandOptionRole           CommandOption = "role"
+	CommandOptionUser           CommandOption = "user"
+	CommandOptionRemove         CommandOption = "remove"
+	CommandOptionProfile        CommandOption = "profile"
+	CommandOptionSendMessages   CommandOption = "send-messages"
+	CommandOptionAttachFiles    CommandOption = "attach-files"
+	CommandOptionAddReactions   CommandOption = "add-reactions"
+	CommandOptionUseThreads     CommandOption = "use-threads"
+	CommandOptionOnDelete       CommandOption = "on-delete"
+	CommandOptionOnEdit         CommandOption = "on-edit"
+)
+
+const (
+	CommandGroupConfig = "config"
+	CommandGroupCohost = "cohost"
+)
+
+const (
+	SubcommandLink   = "link"
+	SubcommandUnlink = "unlink"
+	SubcommandStatus = "status"
+	SubcommandSync   = "sync"
+	SubcommandSetup  = "setup"
+	SubcommandSet    = "set"
+
+	SubcommandManagerRole = "manager-role"
+	SubcommandHostRole    = "host-role"
+	SubcommandStaffRole   = "staff-role"
+	SubcommandPermissions = "permissions"
+	SubcommandPolicy      = "channel-policy"
+	SubcommandAdd         = "add"
+	SubcommandRemove      = "remove"
+)
+
+var minStatusPage float64 = 1
+
+// The root every command of the bot is a subcommand of. Only members with the Manage Server permission see it by
+// default, admins open it to the event manager role and to hosts, who use the cohost subcommands, in the integration
+// settings of the server. isEventManager still checks every use of the subcommands that aren't public, the cohost
+// subcommands check for hosts themselves.
+var cmdEventChannels = discordgo.ApplicationCommand{
+	Name:                     "event-channels",
+	Description:              "Manage event channels",
 	DMPermission:             &dmPermission,
 	DefaultMemberPermissions: &defaultMemberPermissions,
-	Options:                  []*discordgo.ApplicationCommandOption{},
+}
+
+var cmdGroupConfig = discordgo.ApplicationCommandOption{
+	Name:        CommandGroupConfig,
+	Description: "Configure the bot",
+	Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+}
+
+var cmdGroupCohost = discordgo.ApplicationCommandOption{
+	Name:        CommandGroupCohost,
+	Description: "Share the moderation of an event channel",
+	Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+}
+
+var cmdLink = discordgo.ApplicationCommandOption{
+	Name:        SubcommandLink,
+	Description: "Link an existing channel to a scheduled event",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:         CommandOptionEvent,
+			Description:  "The scheduled event name or ID",
+			Type:         discordgo.ApplicationCommandOptionString,
+			Required:     true,
+			Autocomplete: true,
+		},
+		{
+			Name:         CommandOptionChannel,
+			Description:  "The channel to use for the event",
+			Type:         discordgo.ApplicationCommandOptionChannel,
+			ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+			Required:     true,
+		},
+		{
+			Name:        CommandOptionReleaseChannel,
+			Description: "Make the previously linked channel public again",
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+		},
+	},
+}
+
+var cmdUnlink = discordgo.ApplicationCommandOption{
+	Name:        SubcommandUnlink,
+	Description: "Stop managing the channel of a scheduled event",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:         CommandOptionEvent,
+			Description:  "The scheduled event name or ID",
+			Type:         discordgo.ApplicationCommandOptionString,
+			Autocomplete: true,
+		},
+		{
+			Name:         CommandOptionTrackedChannel,
+			Description:  "The event channel to unlink",
+			Type:         discordgo.ApplicationCommandOptionString,
+			Autocomplete: true,
+		},
+		{
+			Name:        CommandOptionReleaseChannel,
+			Description: "Make the unlinked channel public again",
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+		},
+	},
+}
+
+var cmdStatus = discordgo.ApplicationCommandOption{
+	Name:        SubcommandStatus,
+	Description: "Show the bot configuration and tracked events",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        CommandOptionPage,
+			Description: "The page of tracked events to show",
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			MinValue:    &minStatusPage,
+		},
+	},
+}
+
+var cmdSync = discordgo.ApplicationCommandOption{
+	Name:        SubcommandSync,
+	Description: "Run the initial sync",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
+}
+
+var cmdConfigSetup = discordgo.ApplicationCommandOption{
+	Name:        SubcommandSetup,
+	Description: "Walk through the bot configuration",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
+}
+
+var cmdConfigManagerRole = discordgo.ApplicationCommandOption{
+	Name:        SubcommandManagerRole,
+	Description: "Set the role allowed to manage event channels, leave empty to clear it",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        CommandOptionRole,
+			Description: "The event manager role",
+			Type:        discordgo.ApplicationCommandOptionRole,
+		},
+	},
+}
+
+var cmdConfigHostRole = discordgo.ApplicationCommandOption{
+	Name:        SubcommandHostRole,
+	Description: "Give a role the host permissions in every event channel",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        CommandOptionRole,
+			Description: "The host role",
+			Type:        discordgo.ApplicationCommandOptionRole,
+			Required:    true,
+		},
+		{
+			Name:        CommandOptionRemove,
+			Description: "Take the host permissions away from the role instead",
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+		},
+	},
+}
+
+var cmdConfigStaffRole = discordgo.ApplicationCommandOption{
+	Name:        SubcommandStaffRole,
+	Description: "Let a moderator role see and moderate every event channel",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        CommandOptionRole,
+			Description: "The staff role",
+			Type:        discordgo.ApplicationCommandOptionRole,
+			Required:    true,
+		},
+		{
+			Name:        CommandOptionRemove,
+			Description: "Stop giving the role access to event channels instead",
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+		},
+	},
+}
+
+type ParticipantSetting = string
+
+const (
+	ParticipantSettingAllow   ParticipantSetting = "allow"
+	ParticipantSettingDeny    ParticipantSetting = "deny"
+	ParticipantSettingDefault ParticipantSetting = "default"
+)
+
+// The options of the permissions command mapped to the participant permissions they change.
+var participantPermissionOptions = map[CommandOption]int64{
+	CommandOptionSendMessages: discordgo.PermissionSendMessages,
+	CommandOptionAttachFiles:  discordgo.PermissionAttachFiles,
+	CommandOptionAddReactions: discordgo.PermissionAddReactions,
+	CommandOptionUseThreads:   participantThreadPermissions,
+}
+
+var participantSettingChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{Name: "Allow", Value: ParticipantSettingAllow},
+	{Name: "Deny", Value: ParticipantSettingDeny},
+	{Name: "Default", Value: ParticipantSettingDefault},
+}
+
+var cmdConfigPermissions = discordgo.ApplicationCommandOption{
+	Name:        SubcommandPermissions,
+	Description: "Choose who can see event channels and what participants can do",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        CommandOptionProfile,
+			Description: "Who can see event channels",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "Private, only participants", Value: PermissionProfilePrivate},
+				{Name: "Public read-only, only participants can post", Value: PermissionProfilePublicReadOnly},
+				{Name: "Public", Value: PermissionProfilePublic},
+			},
+		},
+		{
+			Name:        CommandOptionSendMessages,
+			Description: "Whether participants can send messages",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Choices:     participantSettingChoices,
+		},
+		{
+			Name:        CommandOptionAttachFiles,
+			Description: "Whether participants can attach files",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Choices:     participantSettingChoices,
+		},
+		{
+			Name:        CommandOptionAddReactions,
+			Description: "Whether participants can add reactions",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Choices:     participantSettingChoices,
+		},
+		{
+			Name:        CommandOptionUseThreads,
+			Description: "Whether participants can create and post in threads",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Choices:     participantSettingChoices,
+		},
+	},
+}
+
+var cmdConfigPolicy = discordgo.ApplicationCommandOption{
+	Name:        SubcommandPolicy,
+	Description: "Choose what happens when someone deletes or edits an event channel",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        CommandOptionOnDelete,
+			Description: "What to do when an event channel is deleted",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "Create it again", Value: ChannelDeletePolicyRecreate},
+				{Name: "Stop tracking the event", Value: ChannelDeletePolicyUntrack},
+			},
+		},
+		{
+			Name:        CommandOptionOnEdit,
+			Description: "What to do when an event channel is renamed or its permissions change",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "Keep the changes", Value: ChannelEditPolicyRespect},
+				{Name: "Revert the changes", Value: ChannelEditPolicyRevert},
+			},
+		},
+	},
+}
+
+var cohostOptions = []*discordgo.ApplicationCommandOption{
+	{
+		Name:        CommandOptionUser,
+		Description: "The co-host",
+		Type:        discordgo.ApplicationCommandOptionUser,
+		Required:    true,
+	},
+	{
+		Name:         CommandOptionEvent,
+		Description:  "The scheduled event name or ID, defaults to the event of this channel",
+		Type:         discordgo.ApplicationCommandOptionString,
+		Autocomplete: true,
+	},
+}
+
+var cmdCohostAdd = discordgo.ApplicationCommandOption{
+	Name:        SubcommandAdd,
+	Description: "Let someone moderate the event channel with you",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
+	Options:     cohostOptions,
+}
+
+var cmdCohostRemove = discordgo.ApplicationCommandOption{
+	Name:        SubcommandRemove,
+	Description: "Stop someone from moderating the event channel",
+	Type:        discordgo.ApplicationCommandOptionSubCommand,
+	Options:     cohostOptions,
+}
+
+func (em *EventManager) newCommandRegistry() *commandRegistry {
+	r := newCommandRegistry(&cmdEventChannels)
+	r.add(&cmdLink, textCommand(em.linkEventChannel))
+	r.add(&cmdUnlink, textCommand(em.unlinkEventChannel))
+	r.add(&cmdStatus, em.handleStatusCommand)
+	r.add(&cmdSync, em.handleSyncCommand)
+	r.addGroup(&cmdGroupConfig,
+		registeredCommand{definition: &cmdConfigSet, handler: em.handleConfigSetCommand},
+		registeredCommand{definition: &cmdConfigSetup, handler: em.handleConfigSetupCommand},
+		registeredCommand{definition: &cmdConfigManagerRole, handler: em.handleConfigManagerRoleCommand},
+		registeredCommand{definition: &cmdConfigHostRole, handler: em.handleConfigHostRoleCommand},
+		registeredCommand{definition: &cmdConfigStaffRole, handler: em.handleConfigStaffRoleCommand},
+		registeredCommand{definition: &cmdConfigPermissions, handler: em.handleConfigPermissionsCommand},
+		registeredCommand{definition: &cmdConfigPolicy, handler: em.handleConfigPolicyCommand},
+	)
+	r.addGroup(&cmdGroupCohost,
+		registeredCommand{definition: &cmdCohostAdd, handler: em.handleCohostAddCommand, public: true},
+		registeredCommand{definition: &cmdCohostRemove, handler: em.handleCohostRemoveCommand, public: true},
+	)
+
+	return r
 }
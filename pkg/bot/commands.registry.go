@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+type commandHandler func(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error)
+
+type registeredCommand struct {
+	definition *discordgo.ApplicationCommandOption
+	handler    commandHandler
+	// Lets members who are not event managers use the command, the handler has to check access itself.
+	public bool
+}
+
+// commandRegistry assembles every subcommand, and subcommand group, under a single root command and routes the
+// interactions for them back to their handlers.
+type commandRegistry struct {
+	root     *discordgo.ApplicationCommand
+	options  []*discordgo.ApplicationCommandOption
+	commands map[string]registeredCommand
+}
+
+func newCommandRegistry(root *discordgo.ApplicationCommand) *commandRegistry {
+	return &commandRegistry{
+		root:     root,
+		commands: map[string]registeredCommand{},
+	}
+}
+
+func (r *commandRegistry) add(definition *discordgo.ApplicationCommandOption, handler commandHandler) {
+	r.options = append(r.options, definition)
+	r.commands[definition.Name] = registeredCommand{definition: definition, handler: handler}
+}
+
+func (r *commandRegistry) addGroup(group *discordgo.ApplicationCommandOption, commands ...registeredCommand) {
+	definition := *group
+	definition.Options = make([]*discordgo.ApplicationCommandOption, 0, len(commands))
+	for _, command := range commands {
+		definition.Options = append(definition.Options, command.definition)
+		r.commands[group.Name+" "+command.definition.Name] = command
+	}
+
+	r.options = append(r.options, &definition)
+}
+
+func (r *commandRegistry) definitions() []*discordgo.ApplicationCommand {
+	root := *r.root
+	root.Options = r.options
+
+	return []*discordgo.ApplicationCommand{&root}
+}
+
+// Identifies the current definitions so they are only sent to Discord when they changed.
+func (r *commandRegistry) hash() (string, error) {
+	b, err := json.Marshal(r.definitions())
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Finds the command for the invoked subcommand along with its path and the options passed to it.
+func (r *commandRegistry) resolve(data discordgo.ApplicationCommandInteractionData) (string, registeredCommand, []*discordgo.ApplicationCommandInteractionDataOption, error) {
+	if data.Name != r.root.Name {
+		return "", registeredCommand{}, nil, fmt.Errorf("unknown command %q", data.Name)
+	}
+	if len(data.Options) != 1 {
+		return "", registeredCommand{}, nil, fmt.Errorf("expected exactly one subcommand")
+	}
+
+	path := data.Options[0].Name
+	options := data.Options[0].Options
+	if data.Options[0].Type == discordgo.ApplicationCommandOptionSubCommandGroup {
+		if len(options) != 1 {
+			return "", registeredCommand{}, nil, fmt.Errorf("expected exactly one subcommand in group %q", path)
+		}
+
+		path += " " + options[0].Name
+		options = options[0].Options
+	}
+
+	command, has := r.commands[path]
+	if !has {
+		return "", registeredCommand{}, nil, fmt.Errorf("unknown subcommand %q", path)
+	}
+
+	return path, command, options, nil
+}
+
+// Finds the handler for the invoked subcommand along with the options passed to it.
+func (r *commandRegistry) route(data discordgo.ApplicationCommandInteractionData) (string, commandHandler, map[string]*discordgo.ApplicationCommandInteractionDataOption, error) {
+	path, command, options, err := r.resolve(data)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return path, command.handler, getOptionsMap(options), nil
+}
+
+func (r *commandRegistry) isPublic(data discordgo.ApplicationCommandInteractionData) bool {
+	_, command, _, err := r.resolve(data)
+	return err == nil && command.public
+}
+
+// Adapts a handler that only answers with text.
+func textCommand(handler func(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guildID string, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (string, error)) commandHandler {
+	return func(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+		reply, err := handler(ctx, log, s, i.GuildID, options)
+		if err != nil {
+			return nil, err
+		}
+
+		return &discordgo.InteractionResponseData{
+			Content: reply,
+		}, nil
+	}
+}
+
+// Overwrites the commands of the scope, a single discordgo.Guild in development or every discordgo.Guild
+// otherwise, unless the definitions registered last time are the same.
+func (em *EventManager) RegisterCommands(ctx context.Context, log *logrus.Entry, session *discordgo.Session) error {
+	hash, err := em.commands.hash()
+	if err != nil {
+		return err
+	}
+
+	registration := &CommandRegistration{ID: session.State.User.ID + ":" + em.devGuildID}
+	found, err := em.engine.Context(ctx).Get(registration)
+	if err != nil {
+		return err
+	}
+
+	if found && registration.Hash == hash {
+		log.Debug("commands unchanged, skipping registration")
+		return nil
+	}
+
+	_, err = session.ApplicationCommandBulkOverwrite(session.State.User.ID, em.devGuildID, em.commands.definitions())
+	if err != nil {
+		return err
+	}
+
+	registration.Hash = hash
+	if found {
+		_, err = em.engine.Context(ctx).ID(registration.ID).Update(registration)
+	} else {
+		_, err = em.engine.Context(ctx).Insert(registration)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.WithField("dev_guild_id", em.devGuildID).Info("registered commands")
+	return nil
+}
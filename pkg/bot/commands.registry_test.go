@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCommandRegistryResolve(t *testing.T) {
+	r := newTestEventManager().newCommandRegistry()
+
+	subcommand := func(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options}
+	}
+	group := func(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionSubCommandGroup, Options: options}
+	}
+	page := &discordgo.ApplicationCommandInteractionDataOption{Name: CommandOptionPage, Type: discordgo.ApplicationCommandOptionInteger, Value: float64(2)}
+
+	tests := []struct {
+		name    string
+		data    discordgo.ApplicationCommandInteractionData
+		path    string
+		options int
+		public  bool
+		fails   bool
+	}{
+		{
+			name:    "subcommand",
+			data:    discordgo.ApplicationCommandInteractionData{Name: cmdEventChannels.Name, Options: []*discordgo.ApplicationCommandInteractionDataOption{subcommand(SubcommandStatus, page)}},
+			path:    SubcommandStatus,
+			options: 1,
+		},
+		{
+			name: "subcommand in a group",
+			data: discordgo.ApplicationCommandInteractionData{Name: cmdEventChannels.Name, Options: []*discordgo.ApplicationCommandInteractionDataOption{group(CommandGroupConfig, subcommand(SubcommandPolicy))}},
+			path: CommandGroupConfig + " " + SubcommandPolicy,
+		},
+		{
+			name:   "public subcommand in a group",
+			data:   discordgo.ApplicationCommandInteractionData{Name: cmdEventChannels.Name, Options: []*discordgo.ApplicationCommandInteractionDataOption{group(CommandGroupCohost, subcommand(SubcommandRemove))}},
+			path:   CommandGroupCohost + " " + SubcommandRemove,
+			public: true,
+		},
+		{
+			name:  "subcommand of another group",
+			data:  discordgo.ApplicationCommandInteractionData{Name: cmdEventChannels.Name, Options: []*discordgo.ApplicationCommandInteractionDataOption{group(CommandGroupCohost, subcommand(SubcommandPolicy))}},
+			fails: true,
+		},
+		{
+			name:  "unknown root",
+			data:  discordgo.ApplicationCommandInteractionData{Name: "other", Options: []*discordgo.ApplicationCommandInteractionDataOption{subcommand(SubcommandStatus)}},
+			fails: true,
+		},
+		{
+			name:  "unknown subcommand",
+			data:  discordgo.ApplicationCommandInteractionData{Name: cmdEventChannels.Name, Options: []*discordgo.ApplicationCommandInteractionDataOption{subcommand("other")}},
+			fails: true,
+		},
+		{
+			name:  "no subcommand",
+			data:  discordgo.ApplicationCommandInteractionData{Name: cmdEventChannels.Name},
+			fails: true,
+		},
+		{
+			name:  "empty group",
+			data:  discordgo.ApplicationCommandInteractionData{Name: cmdEventChannels.Name, Options: []*discordgo.ApplicationCommandInteractionDataOption{group(CommandGroupConfig)}},
+			fails: true,
+		},
+		{
+			name:  "group used as a subcommand",
+			data:  discordgo.ApplicationCommandInteractionData{Name: cmdEventChannels.Name, Options: []*discordgo.ApplicationCommandInteractionDataOption{subcommand(CommandGroupConfig)}},
+			fails: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, _, options, err := r.resolve(test.data)
+			if (err != nil) != test.fails {
+				t.Fatalf("got error %v, want failure %v", err, test.fails)
+			}
+			if path != test.path {
+				t.Errorf("got path %q, want %q", path, test.path)
+			}
+			if len(options) != test.options {
+				t.Errorf("got %d options, want %d", len(options), test.options)
+			}
+			if public := r.isPublic(test.data); public != test.public {
+				t.Errorf("got public %v, want %v", public, test.public)
+			}
+		})
+	}
+}
+
+func TestCommandRegistryDefinitions(t *testing.T) {
+	definitions := newTestEventManager().newCommandRegistry().definitions()
+
+	// Every command is grouped under the single root.
+	if len(definitions) != 1 || definitions[0].Name != cmdEventChannels.Name {
+		t.Fatalf("got %d root commands, want only %s", len(definitions), cmdEventChannels.Name)
+	}
+
+	root := definitions[0]
+	if root.DefaultMemberPermissions == nil || *root.DefaultMemberPermissions != discordgo.PermissionManageServer {
+		t.Errorf("%s should default to members with Manage Server", root.Name)
+	}
+
+	var cohost *discordgo.ApplicationCommandOption
+	for _, option := range root.Options {
+		if option.Name == CommandGroupCohost {
+			cohost = option
+		}
+	}
+	if cohost == nil {
+		t.Fatalf("missing the %s group", CommandGroupCohost)
+	}
+	if cohost.Type != discordgo.ApplicationCommandOptionSubCommandGroup || len(cohost.Options) != 2 {
+		t.Errorf("%s should be a group of 2 subcommands", cohost.Name)
+	}
+
+	// The registry must not change the shared definitions it was built from.
+	if len(cmdEventChannels.Options) != 0 || len(cmdGroupCohost.Options) != 0 {
+		t.Error("shared definitions were modified")
+	}
+}
@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// Members with any of these may always manage the bot, regardless of the configured manager role.
+const serverManagementPermissions = discordgo.PermissionManageServer | discordgo.PermissionAdministrator
+
+// Whether the discordgo.Member may use the bot, either through Discord's server management permissions or the
+// event manager role configured for the discordgo.Guild.
+func (em *EventManager) isEventManager(ctx context.Context, guildID string, member *discordgo.Member) (bool, error) {
+	if member == nil {
+		return false, nil
+	}
+
+	if hasServerManagement(member) {
+		return true, nil
+	}
+
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(guildID).Cols("manager_role_id").Get(&guild)
+	if err != nil {
+		return false, err
+	}
+	if !found || guild.ManagerRoleID == "" {
+		return false, nil
+	}
+
+	for _, roleID := range member.Roles {
+		if roleID == guild.ManagerRoleID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Whether the user behind the interaction may use it. Components in direct messages carry no discordgo.Member, so
+// the discordgo.Guild is taken from their CustomID and the member looked up there.
+func (em *EventManager) authorizeInteraction(ctx context.Context, s *discordgo.Session, i *interactionContext) (bool, error) {
+	if em.isPublicInteraction(i) {
+		return true, nil
+	}
+	if i.GuildID != "" {
+		return em.isEventManager(ctx, i.GuildID, i.Member)
+	}
+	if !i.isComponent() {
+		return false, nil
+	}
+
+	guildID, err := em.getComponentGuildID(ctx, i)
+	if err != nil || guildID == "" {
+		return false, err
+	}
+
+	member, err := s.GuildMember(guildID, i.userID())
+	if err != nil {
+		if isDiscordErrRESTCode(err, http.StatusNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	member.Permissions, err = getMemberGuildPermissions(s, guildID, member)
+	if err != nil {
+		return false, err
+	}
+
+	return em.isEventManager(ctx, guildID, member)
+}
+
+// The discordgo.Guild a component belongs to, empty when its CustomID doesn't name one.
+func (em *EventManager) getComponentGuildID(ctx context.Context, i *interactionContext) (string, error) {
+	var customID string
+	if i.Type == discordgo.InteractionMessageComponent {
+		customID = i.MessageComponentData().CustomID
+	} else {
+		customID = i.ModalSubmitData().CustomID
+	}
+
+	id, err := em.decodeComponentID(customID)
+	if err != nil {
+		return "", nil
+	}
+
+	switch id.Namespace {
+	case setupComponentNamespace, onboardingComponentNamespace:
+		return id.SessionID, nil
+	case syncComponentNamespace:
+		session := &WizardSession{ID: id.SessionID}
+		_, err = em.engine.Context(ctx).Get(session)
+		return session.GuildID, err
+	}
+
+	return "", nil
+}
+
+// Whether anyone may use the interaction, leaving the access checks to its handler.
+func (em *EventManager) isPublicInteraction(i *interactionContext) bool {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+		return em.commands.isPublic(i.ApplicationCommandData())
+	case discordgo.InteractionMessageComponent:
+		id, err := em.decodeComponentID(i.MessageComponentData().CustomID)
+		return err == nil && id.Namespace == membershipComponentNamespace
+	}
+
+	return false
+}
+
+func hasServerManagement(member *discordgo.Member) bool {
+	return member != nil && member.Permissions&serverManagementPermissions != 0
+}
+
+// Answers an interaction from someone who is not allowed to use the bot.
+func denyInteraction(log *logrus.Entry, i *interactionContext) error {
+	log.Warn("interaction from someone who is not an event manager")
+
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		return i.autocomplete(nil)
+	}
+
+	return i.respond(&discordgo.InteractionResponseData{
+		Content: "You need the Manage Server permission or the event manager role to use this.",
+	})
+}
@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// Discord rejects autocomplete responses with more choices than this.
+const maxAutocompleteChoices = 25
+
+// Discord rejects choice names longer than this.
+const maxChoiceNameLength = 100
+
+func (em *EventManager) handleAutocomplete(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext) error {
+	focused := getFocusedOption(i.ApplicationCommandData().Options)
+	if focused == nil {
+		return fmt.Errorf("no focused option")
+	}
+
+	query := strings.ToLower(focused.StringValue())
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	var err error
+	switch focused.Name {
+	case CommandOptionEvent:
+		choices, err = em.getEventChoices(ctx, s, i.GuildID, query)
+	case CommandOptionTrackedChannel:
+		choices, err = em.getTrackedChannelChoices(ctx, s, i.GuildID, query)
+	default:
+		log.WithField("option", focused.Name).Warn("no autocomplete for option")
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(choices) > maxAutocompleteChoices {
+		choices = choices[:maxAutocompleteChoices]
+	}
+
+	return i.autocomplete(choices)
+}
+
+// Suggests scheduled events, and tracked Events whose scheduled event is gone, whose name contains the query.
+func (em *EventManager) getEventChoices(ctx context.Context, s *discordgo.Session, guildID string, query string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	scheduledEvents, err := em.getCachedScheduledEvents(s, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, maxAutocompleteChoices)
+	for _, event := range scheduledEvents {
+		seen[event.ID] = true
+		if !strings.Contains(strings.ToLower(event.Name), query) {
+			continue
+		}
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  truncateChoiceName(event.Name),
+			Value: event.ID,
+		})
+	}
+
+	var events []*Event
+	err = em.engine.Context(ctx).Where("guild_id = ?", guildID).Find(&events)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		if seen[event.ID] {
+			continue
+		}
+
+		name := event.ID
+		if channel := getStateChannel(s, event.ChannelID); channel != nil {
+			name = "#" + channel.Name
+		}
+		if !strings.Contains(strings.ToLower(name), query) {
+			continue
+		}
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  truncateChoiceName(name + " (ended)"),
+			Value: event.ID,
+		})
+	}
+
+	return choices, nil
+}
+
+// Suggests only the discordgo.Channel instances managed by the bot.
+func (em *EventManager) getTrackedChannelChoices(ctx context.Context, s *discordgo.Session, guildID string, query string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	var events []*Event
+	err := em.engine.Context(ctx).Where("guild_id = ? AND channel_id <> ''", guildID).Find(&events)
+	if err != nil {
+		return nil, err
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, maxAutocompleteChoices)
+	for _, event := range events {
+		channel := getStateChannel(s, event.ChannelID)
+		if channel == nil {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(channel.Name), query) {
+			continue
+		}
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  truncateChoiceName("#" + channel.Name),
+			Value: channel.ID,
+		})
+	}
+
+	return choices, nil
+}
+
+func (em *EventManager) getCachedScheduledEvents(s *discordgo.Session, guildID string) ([]*discordgo.GuildScheduledEvent, error) {
+	return em.scheduledEvents.get(guildID, func() ([]*discordgo.GuildScheduledEvent, error) {
+		return s.GuildScheduledEvents(guildID, false)
+	})
+}
+
+func getFocusedOption(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, option := range options {
+		if option.Focused {
+			return option
+		}
+
+		if focused := getFocusedOption(option.Options); focused != nil {
+			return focused
+		}
+	}
+
+	return nil
+}
+
+func getStateChannel(s *discordgo.Session, channelID string) *discordgo.Channel {
+	if channelID == "" {
+		return nil
+	}
+
+	channel, err := s.State.Channel(channelID)
+	if err != nil {
+		return nil
+	}
+
+	return channel
+}
+
+func truncateChoiceName(name string) string {
+	runes := []rune(name)
+	if len(runes) <= maxChoiceNameLength {
+		return name
+	}
+
+	return string(runes[:maxChoiceNameLength-1]) + "…"
+}
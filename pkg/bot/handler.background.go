@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"context"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// How often the periodic work of the EventManager runs.
+const backgroundTaskInterval = time.Hour
+
+// Runs the periodic work of the EventManager until the context is done.
+func (em *EventManager) RunBackgroundTasks(ctx context.Context, s *discordgo.Session) {
+	log := em.logger.WithFields(logrus.Fields{
+		"method": "RunBackgroundTasks",
+	})
+
+	ticker := time.NewTicker(backgroundTaskInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := em.nudgeUnconfiguredGuilds(ctx, log, s)
+		if err != nil {
+			log.WithError(err).Error("failed to nudge unconfigured guilds")
+		}
+
+		err = em.renewEventInvites(ctx, log, s)
+		if err != nil {
+			log.WithError(err).Error("failed to renew event invites")
+		}
+
+		err = em.purgeRemovedGuilds(ctx, log)
+		if err != nil {
+			log.WithError(err).Error("failed to purge removed guilds")
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+type ChannelDeletePolicy = string
+
+const (
+	// Creates the event channel again, letting the participants back in.
+	ChannelDeletePolicyRecreate ChannelDeletePolicy = "recreate"
+	// Stops managing the event, like unlinking it.
+	ChannelDeletePolicyUntrack ChannelDeletePolicy = "untrack"
+)
+
+type ChannelEditPolicy = string
+
+const (
+	// Keeps manual renames and permission changes, the bot stops updating what was changed.
+	ChannelEditPolicyRespect ChannelEditPolicy = "respect"
+	// Puts the name and permissions the bot manages back.
+	ChannelEditPolicyRevert ChannelEditPolicy = "revert"
+)
+
+// Creates the discordgo.Channel for the discordgo.GuildScheduledEvent, private to its participants and hosts.
+func (em *EventManager) createEventChannel(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild, scheduledEvent *discordgo.GuildScheduledEvent, event *Event) (*discordgo.Channel, error) {
+	atEveryoneRole, err := getAtEveryoneRole(s, guild.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID, err := em.getEventChannelParentID(ctx, log, s, guild)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find a category with room: %w", err)
+	}
+
+	permissionOverwrites, err := getEventPermissionOverwrites(s, guild, scheduledEvent, event, atEveryoneRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permission overwrites: %w", err)
+	}
+
+	channel, err := s.GuildChannelCreateComplex(guild.ID, discordgo.GuildChannelCreateData{
+		Name:                 eventChannelName(scheduledEvent.Name),
+		Type:                 discordgo.ChannelTypeGuildText,
+		Topic:                guild.GetEventChannelTopic(scheduledEvent),
+		ParentID:             parentID,
+		PermissionOverwrites: permissionOverwrites,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel: %w", err)
+	}
+
+	return channel, nil
+}
+
+// Someone deleted a discordgo.Channel, if it belonged to an Event we follow the ChannelDeletePolicy of the Guild.
+func (em *EventManager) onChannelDelete(ctx context.Context, log *logrus.Entry, s *discordgo.Session, m *discordgo.ChannelDelete) error {
+	if m.GuildID == "" {
+		return nil
+	}
+
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(m.GuildID).Get(&guild)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+
+	if m.ID == guild.EventAnnouncementChannelID || m.ID == guild.EventChannelParentID {
+		return em.onConfiguredChannelDeleted(ctx, log, s, &guild, m.ID, m.Name)
+	}
+
+	if containsString(guild.OverflowCategoryIDs, m.ID) || containsString(guild.OverflowCategoryIDs, m.ParentID) {
+		err = em.collapseOverflowCategories(ctx, log, s, &guild)
+		if err != nil {
+			log.WithError(err).Warn("failed to collapse overflow categories")
+		}
+	}
+
+	event := &Event{}
+	found, err = em.engine.Context(ctx).Where("guild_id = ? AND channel_id = ?", m.GuildID, m.ID).Get(event)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+
+	// We delete channels ourselves once their event is over, those must stay gone.
+	scheduledEvent, err := s.GuildScheduledEvent(m.GuildID, event.ID, false)
+	if err != nil {
+		if isDiscordErrRESTCode(err, http.StatusNotFound) {
+			return nil
+		}
+		return err
+	}
+	if scheduledEvent.Status == discordgo.GuildScheduledEventStatusCompleted || scheduledEvent.Status == discordgo.GuildScheduledEventStatusCanceled {
+		return nil
+	}
+
+	return em.replaceDeletedEventChannel(ctx, log.WithField("event_id", event.ID), s, &guild, event, scheduledEvent)
+}
+
+// The announcement channel or the category of the Guild is gone. Announcements fall back to the system channel, or
+// stop, and new event channels are created outside of any category until the owner picks new ones.
+func (em *EventManager) onConfiguredChannelDeleted(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild, channelID string, name string) error {
+	deleted := fmt.Sprintf("`%s`", channelID)
+	if name != "" {
+		deleted = "#" + name
+	}
+
+	var notice string
+	switch channelID {
+	case guild.EventAnnouncementChannelID:
+		guild.EventAnnouncementChannelID = ""
+		notice = fmt.Sprintf("The announcement channel %s was deleted, new events are no longer announced.", deleted)
+
+		discordGuild, err := s.State.Guild(guild.ID)
+		if err == nil && discordGuild.SystemChannelID != "" && discordGuild.SystemChannelID != channelID {
+			guild.EventAnnouncementChannelID = discordGuild.SystemChannelID
+			notice = fmt.Sprintf("The announcement channel %s was deleted, new events are announced in %s for now.",
+				deleted, describeChannel(s, discordGuild.SystemChannelID))
+		}
+	case guild.EventChannelParentID:
+		guild.EventChannelParentID = ""
+		notice = fmt.Sprintf("The category %s was deleted, new event channels are created outside of any category.", deleted)
+	default:
+		return nil
+	}
+
+	_, err := em.engine.Context(ctx).ID(guild.ID).Cols("event_announcement_channel_id", "event_channel_parent_id").Update(guild)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("deleted_channel_id", channelID).Warn("configured channel was deleted")
+
+	err = em.notifyOwner(s, guild.ID, notice+" Run `/event-channels config setup` to choose another one.")
+	if err != nil {
+		log.WithError(err).Warn("failed to tell the owner about the deleted channel")
+	}
+
+	return nil
+}
+
+// Recreates the discordgo.Channel of the Event with its participants, or untracks the Event, depending on the
+// ChannelDeletePolicy of the Guild.
+func (em *EventManager) replaceDeletedEventChannel(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild, event *Event, scheduledEvent *discordgo.GuildScheduledEvent) error {
+	if guild.ChannelDeletePolicy == ChannelDeletePolicyUntrack {
+		// The invite and the info message went with the channel.
+		event.ChannelID = ""
+		event.Unlinked = true
+		event.InviteCode = ""
+		event.InviteExpiresAt = nil
+		event.InfoMessageID = ""
+		_, err := em.engine.Context(ctx).ID(event.ID).Cols("channel_id", "unlinked", "invite_code", "invite_expires_at", "info_message_id").Update(event)
+		if err != nil {
+			return err
+		}
+
+		if len(event.VoiceAccessIDs) > 0 {
+			err = em.revokeAllVoiceAccess(ctx, s, event)
+			if err != nil {
+				log.WithError(err).Warn("failed to revoke voice access")
+			}
+		}
+
+		log.Info("event channel was deleted, untracked the event")
+		return nil
+	}
+
+	channel, err := em.recreateEventChannel(ctx, log, s, guild, event, scheduledEvent)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.ChannelMessageSend(channel.ID, "This channel was deleted and has been created again for the event.")
+	if err != nil {
+		log.WithError(err).Warn("failed to post in recreated channel")
+	}
+
+	log.WithField("channel_id", channel.ID).Info("recreated deleted event channel")
+	return nil
+}
+
+// Creates a new discordgo.Channel for an Event that lost its own, keeping the co-hosts and members of the Event
+// while resetting what belonged to the previous channel.
+func (em *EventManager) recreateEventChannel(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild, event *Event, scheduledEvent *discordgo.GuildScheduledEvent) (*discordgo.Channel, error) {
+	channel, err := em.createEventChannel(ctx, log, s, guild, scheduledEvent, event)
+	if err != nil {
+		return nil, err
+	}
+
+	event.ChannelID = channel.ID
+	event.NameLocked = false
+	event.PermissionsLocked = false
+	event.InfoMessageID = ""
+	event.SentChannelName = eventChannelName(scheduledEvent.Name)
+	event.ChannelName = channel.Name
+	_, err = em.engine.Context(ctx).ID(event.ID).Cols("channel_id", "name_locked", "permissions_locked", "info_message_id", "sent_channel_name", "channel_name").Update(event)
+	if err != nil {
+		if _, err := s.ChannelDelete(channel.ID); err != nil {
+			log.WithError(err).Errorf("failed to cleanup channel %q", channel.ID)
+		}
+		return nil, err
+	}
+
+	err = em.updateEventInfoMessage(ctx, log, s, guild.ID, event.ID)
+	if err != nil {
+		log.WithError(err).Warn("failed to post info message")
+	}
+
+	err = em.orderEventChannels(ctx, log, s, guild)
+	if err != nil {
+		log.WithError(err).Warn("failed to order event channels")
+	}
+
+	return channel, nil
+}
+
+// Channel updates arriving this soon after the bot edited the channel are taken to be caused by that edit.
+const ownChannelEditWindow = 10 * time.Second
+
+// Marks the discordgo.Channel as about to be edited by the bot, so onChannelUpdate doesn't take the edit for a
+// manual one.
+func (em *EventManager) markOwnChannelEdit(channelID string) {
+	em.ownEdits.set(channelID, true)
+}
+
+// Applies the edit to the event discordgo.Channel, remembering the name the bot asked for and the one Discord made
+// of it when the edit renames the channel.
+func (em *EventManager) editEventChannel(ctx context.Context, s *discordgo.Session, event *Event, edit *discordgo.ChannelEdit) (*discordgo.Channel, error) {
+	em.markOwnChannelEdit(event.ChannelID)
+	channel, err := s.ChannelEditComplex(event.ChannelID, edit)
+	if err != nil {
+		return nil, err
+	}
+
+	if edit.Name != "" {
+		event.SentChannelName = edit.Name
+		event.ChannelName = channel.Name
+		_, err = em.engine.Context(ctx).ID(event.ID).Cols("sent_channel_name", "channel_name").Update(event)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return channel, nil
+}
+
+// Someone edited a discordgo.Channel, if it belongs to an Event and its name or permissions no longer match what the
+// bot manages, we follow the ChannelEditPolicy of the Guild.
+func (em *EventManager) onChannelUpdate(ctx context.Context, log *logrus.Entry, s *discordgo.Session, m *discordgo.ChannelUpdate) error {
+	if m.GuildID == "" {
+		return nil
+	}
+
+	event := &Event{}
+	found, err := em.engine.Context(ctx).Where("guild_id = ? AND channel_id = ?", m.GuildID, m.ID).Get(event)
+	if err != nil {
+		return err
+	}
+	if !found || (event.NameLocked && event.PermissionsLocked) {
+		return nil
+	}
+
+	// Discord echoes the edits of the bot itself, those are not for the ChannelEditPolicy to judge.
+	if _, own := em.ownEdits.peek(m.ID); own {
+		return nil
+	}
+
+	var guild Guild
+	found, err = em.engine.Context(ctx).ID(m.GuildID).Get(&guild)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+
+	scheduledEvents, err := em.getCachedScheduledEvents(s, m.GuildID)
+	if err != nil {
+		return err
+	}
+
+	var scheduledEvent *discordgo.GuildScheduledEvent
+	for _, candidate := range scheduledEvents {
+		if candidate.ID == event.ID {
+			scheduledEvent = candidate
+		}
+	}
+	if scheduledEvent == nil {
+		return nil
+	}
+
+	log = log.WithField("event_id", event.ID)
+	respect := guild.ChannelEditPolicy != ChannelEditPolicyRevert
+
+	// Discord may store a different name than the one the bot sent, so compare against what it made of it. Channels
+	// the bot never renamed since it started recording names are left to reconcile.
+	if !event.NameLocked && event.ChannelName != "" && m.Name != event.ChannelName {
+		if respect {
+			event.NameLocked = true
+			log.Info("event channel was renamed, locking its name")
+		} else {
+			_, err = em.editEventChannel(ctx, s, event, &discordgo.ChannelEdit{
+				Name:     eventChannelName(scheduledEvent.Name),
+				Position: m.Position,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to revert channel name: %w", err)
+			}
+			log.Info("event channel was renamed, reverted its name")
+		}
+	}
+
+	if !event.PermissionsLocked {
+		atEveryoneRole, err := getAtEveryoneRole(s, m.GuildID)
+		if err != nil {
+			return err
+		}
+
+		permissionOverwrites, err := getEventPermissionOverwrites(s, &guild, scheduledEvent, event, atEveryoneRole)
+		if err != nil {
+			return err
+		}
+
+		if !equalPermissionOverwrites(m.PermissionOverwrites, permissionOverwrites) {
+			if respect {
+				event.PermissionsLocked = true
+				log.Info("event channel permissions were changed, locking them")
+			} else {
+				err = em.syncEventChannelPermissions(s, &guild, event, scheduledEvent, m.Channel, atEveryoneRole)
+				if err != nil {
+					return fmt.Errorf("failed to revert channel permissions: %w", err)
+				}
+				log.Info("event channel permissions were changed, reverted them")
+			}
+		}
+	}
+
+	if respect {
+		_, err = em.engine.Context(ctx).ID(event.ID).Cols("name_locked", "permissions_locked").Update(event)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Discord refuses to put more channels than this in a category.
+const maxCategoryChannels = 50
+
+// The category new event channels go in: the configured one, or the first overflow category with room, creating
+// a new overflow category once all of them are full.
+func (em *EventManager) getEventChannelParentID(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild) (string, error) {
+	if guild.EventChannelParentID == "" {
+		return "", nil
+	}
+
+	channels, err := s.GuildChannels(guild.ID)
+	if err != nil {
+		return "", err
+	}
+
+	channelIDMap := map[string]*discordgo.Channel{}
+	children := map[string]int{}
+	for _, channel := range channels {
+		channelIDMap[channel.ID] = channel
+		children[channel.ParentID]++
+	}
+
+	category, has := channelIDMap[guild.EventChannelParentID]
+	if !has || children[category.ID] < maxCategoryChannels {
+		return guild.EventChannelParentID, nil
+	}
+
+	for _, overflowID := range guild.OverflowCategoryIDs {
+		if _, has := channelIDMap[overflowID]; has && children[overflowID] < maxCategoryChannels {
+			return overflowID, nil
+		}
+	}
+
+	overflow, err := s.GuildChannelCreateComplex(guild.ID, discordgo.GuildChannelCreateData{
+		Name:                 fmt.Sprintf("%s %d", category.Name, len(guild.OverflowCategoryIDs)+2),
+		Type:                 discordgo.ChannelTypeGuildCategory,
+		Position:             category.Position + len(guild.OverflowCategoryIDs) + 1,
+		PermissionOverwrites: category.PermissionOverwrites,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	guild.OverflowCategoryIDs = append(guild.OverflowCategoryIDs, overflow.ID)
+	_, err = em.engine.Context(ctx).ID(guild.ID).Cols("overflow_category_ids").Update(guild)
+	if err != nil {
+		return "", err
+	}
+
+	log.WithField("category_id", overflow.ID).Info("created overflow category")
+	return overflow.ID, nil
+}
+
+// Deletes the overflow categories that have no channels left and forgets the ones that are gone.
+func (em *EventManager) collapseOverflowCategories(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild) error {
+	if len(guild.OverflowCategoryIDs) == 0 {
+		return nil
+	}
+
+	channels, err := s.GuildChannels(guild.ID)
+	if err != nil {
+		return err
+	}
+
+	exists := map[string]bool{}
+	children := map[string]int{}
+	for _, channel := range channels {
+		exists[channel.ID] = true
+		children[channel.ParentID]++
+	}
+
+	remaining := make([]string, 0, len(guild.OverflowCategoryIDs))
+	for _, overflowID := range guild.OverflowCategoryIDs {
+		if !exists[overflowID] {
+			continue
+		}
+
+		if children[overflowID] == 0 {
+			_, err = s.ChannelDelete(overflowID)
+			if err != nil && !isDiscordErrRESTCode(err, http.StatusNotFound) {
+				log.WithError(err).WithField("category_id", overflowID).Warn("failed to delete empty overflow category")
+				remaining = append(remaining, overflowID)
+				continue
+			}
+
+			log.WithField("category_id", overflowID).Info("deleted empty overflow category")
+			continue
+		}
+
+		remaining = append(remaining, overflowID)
+	}
+
+	if len(remaining) == len(guild.OverflowCategoryIDs) {
+		return nil
+	}
+
+	guild.OverflowCategoryIDs = remaining
+	_, err = em.engine.Context(ctx).ID(guild.ID).Cols("overflow_category_ids").Update(guild)
+	return err
+}
@@ -0,0 +1,183 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+func (em *EventManager) handleCohostAddCommand(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+	return em.updateCohost(ctx, log, s, i, options, true)
+}
+
+func (em *EventManager) handleCohostRemoveCommand(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+	return em.updateCohost(ctx, log, s, i, options, false)
+}
+
+// Adds or removes a co-host of an Event. Only the creator of the discordgo.GuildScheduledEvent and event managers may
+// do so, as anyone can run the cohost commands.
+func (em *EventManager) updateCohost(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption, add bool) (*discordgo.InteractionResponseData, error) {
+	event, scheduledEvent, reply, err := em.findCohostEvent(ctx, s, i, options)
+	if err != nil {
+		return nil, err
+	}
+	if reply != "" {
+		return &discordgo.InteractionResponseData{Content: reply}, nil
+	}
+
+	if i.userID() != scheduledEvent.CreatorID {
+		allowed, err := em.isEventManager(ctx, i.GuildID, i.Member)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return &discordgo.InteractionResponseData{
+				Content: "Only the host of the event can change its co-hosts.",
+			}, nil
+		}
+	}
+
+	userID := options[CommandOptionUser].UserValue(nil).ID
+	log = log.WithFields(logrus.Fields{
+		"event_id":  event.ID,
+		"cohost_id": userID,
+	})
+
+	if userID == scheduledEvent.CreatorID || userID == s.State.User.ID {
+		return &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("<@%s> can't be a co-host of `%s`.", userID, scheduledEvent.Name),
+		}, nil
+	}
+
+	// Re-read under the lock of the Event, the Join button may have changed its members meanwhile.
+	changed := false
+	err = em.modifyEvent(ctx, event, func(event *Event) bool {
+		if containsString(event.CohostIDs, userID) == add {
+			return false
+		}
+
+		if add {
+			event.CohostIDs = append(event.CohostIDs, userID)
+		} else {
+			event.CohostIDs = removeString(event.CohostIDs, userID)
+		}
+		changed = true
+		return true
+	}, "cohost_ids")
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Nothing changed, <@%s> already is %s.", userID, cohostStatus(add)),
+		}, nil
+	}
+
+	if event.ChannelID != "" {
+		var guild Guild
+		_, err = em.engine.Context(ctx).ID(i.GuildID).Get(&guild)
+		if err != nil {
+			return nil, err
+		}
+
+		err = em.applyCohostPermissions(s, &guild, event, userID, add)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update co-host permissions: %w", err)
+		}
+
+		em.scheduleEventInfoUpdate(log, s, i.GuildID, event.ID)
+	}
+
+	log.WithField("add", add).Info("updated co-host")
+
+	return &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("<@%s> now is %s of `%s`.", userID, cohostStatus(add), scheduledEvent.Name),
+	}, nil
+}
+
+// Finds the Event from the event option, or the one whose discordgo.Channel the command was run in. A reply is
+// returned instead when there is no such Event.
+func (em *EventManager) findCohostEvent(ctx context.Context, s *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*Event, *discordgo.GuildScheduledEvent, string, error) {
+	event := &Event{}
+	var found bool
+	var err error
+	if options[CommandOptionEvent] != nil {
+		query := options[CommandOptionEvent].StringValue()
+		scheduledEvent, err := findScheduledEvent(s, i.GuildID, query)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if scheduledEvent == nil {
+			return nil, nil, fmt.Sprintf("Could not find a scheduled event matching `%s`.", query), nil
+		}
+
+		found, err = em.engine.Context(ctx).Where("guild_id = ? AND id = ?", i.GuildID, scheduledEvent.ID).Get(event)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if !found {
+			return nil, nil, fmt.Sprintf("`%s` is not tracked by the bot.", scheduledEvent.Name), nil
+		}
+
+		return event, scheduledEvent, "", nil
+	}
+
+	found, err = em.engine.Context(ctx).Where("guild_id = ? AND channel_id = ?", i.GuildID, i.ChannelID).Get(event)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if !found {
+		return nil, nil, "Run this in an event channel or pick an event.", nil
+	}
+
+	scheduledEvent, err := s.GuildScheduledEvent(i.GuildID, event.ID, false)
+	if err != nil {
+		if isDiscordErrRESTCode(err, http.StatusNotFound) {
+			return nil, nil, "The event of this channel is over.", nil
+		}
+		return nil, nil, "", err
+	}
+
+	return event, scheduledEvent, "", nil
+}
+
+// Grants the host permissions to a new co-host, or takes them back leaving only what a participant gets.
+func (em *EventManager) applyCohostPermissions(s *discordgo.Session, guild *Guild, event *Event, userID string, add bool) error {
+	if add {
+		return em.setEventMemberPermissions(s, guild, event.ChannelID, userID, getHostPermissions(guild), 0)
+	}
+
+	// Members who joined with the button keep their access like interested users do.
+	participant := containsString(event.MemberIDs, userID)
+	if !participant {
+		interested, err := isScheduledEventUser(s, event.GuildID, event.ID, userID)
+		if err != nil {
+			return err
+		}
+		participant = interested
+	}
+
+	if participant {
+		allow, deny := getParticipantPermissions(guild)
+		return em.setEventMemberPermissions(s, guild, event.ChannelID, userID, allow, deny)
+	}
+
+	em.markOwnChannelEdit(event.ChannelID)
+	err := s.ChannelPermissionDelete(event.ChannelID, userID)
+	if err != nil && !isDiscordErrRESTCode(err, http.StatusNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+func cohostStatus(isCohost bool) string {
+	if isCohost {
+		return "a co-host"
+	}
+
+	return "not a co-host"
+}
@@ -0,0 +1,582 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// Routes the subcommands of the /event-channels command through the commandRegistry.
+func (em *EventManager) handleCommand(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext) error {
+	path, handler, options, err := em.commands.route(i.ApplicationCommandData())
+	if err != nil {
+		return err
+	}
+
+	log = log.WithField("command", path)
+
+	err = i.deferResponse()
+	if err != nil {
+		return err
+	}
+
+	response, err := handler(ctx, log, s, i, options)
+	if err != nil {
+		return err
+	}
+
+	err = i.respond(response)
+	if err != nil {
+		return fmt.Errorf("failed to reply to command: %w", err)
+	}
+
+	return nil
+}
+
+func (em *EventManager) handleStatusCommand(ctx context.Context, _ *logrus.Entry, s *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+	return em.getStatus(ctx, s, i.GuildID, options)
+}
+
+func (em *EventManager) handleSyncCommand(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, _ map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+	content, components, err := em.startSyncWizard(ctx, log, s, i.GuildID, i.userID())
+	if err != nil {
+		return nil, err
+	}
+
+	return &discordgo.InteractionResponseData{
+		Content:    content,
+		Components: components,
+	}, nil
+}
+
+func (em *EventManager) handleConfigSetupCommand(ctx context.Context, _ *logrus.Entry, s *discordgo.Session, i *interactionContext, _ map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+	return em.startSetup(ctx, s, i.GuildID, i.userID())
+}
+
+func (em *EventManager) handleConfigSetCommand(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(i.GuildID).Get(&guild)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, fmt.Errorf("could not find guild")
+	}
+
+	errMessage := getConfigOptionsMap(s, options, &guild)
+	if errMessage != "" {
+		return &discordgo.InteractionResponseData{
+			Content: errMessage,
+		}, nil
+	}
+
+	var reply string
+	guild.ConfigurationWasRun = true
+	_, err = em.engine.ID(i.GuildID).UseBool().Update(guild)
+	if err != nil {
+		log.WithError(err).Error("failed to update guild message")
+		reply = "Failed to update config settings."
+	} else {
+		reply = "Successfully updated config settings!"
+
+		if options[ConfigOptionChannelTopic] != nil || options[ConfigOptionVoiceAccess] != nil {
+			err = em.scheduleGuildEventChannelUpdates(ctx, log, s, guild.ID)
+			if err != nil {
+				log.WithError(err).Warn("failed to schedule event channel updates")
+			}
+		}
+
+		problems, _, err := getMissingBotPermissions(s, &guild)
+		if err != nil {
+			return nil, err
+		}
+		if len(problems) > 0 {
+			reply += "\n\n⚠️ " + formatPermissionProblems(problems)
+		}
+	}
+
+	return &discordgo.InteractionResponseData{
+		Content: reply,
+	}, nil
+}
+
+// Only members with server management may choose who else manages the bot, so the role can't grant itself to others.
+func (em *EventManager) handleConfigManagerRoleCommand(ctx context.Context, log *logrus.Entry, _ *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+	if !hasServerManagement(i.Member) {
+		return &discordgo.InteractionResponseData{
+			Content: "Only members with the Manage Server permission can change the manager role.",
+		}, nil
+	}
+
+	guild := Guild{}
+	if options[CommandOptionRole] != nil {
+		guild.ManagerRoleID = options[CommandOptionRole].RoleValue(nil, i.GuildID).ID
+	}
+
+	_, err := em.engine.Context(ctx).ID(i.GuildID).Cols("manager_role_id").Update(&guild)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithField("manager_role_id", guild.ManagerRoleID).Info("updated manager role")
+
+	if guild.ManagerRoleID == "" {
+		return &discordgo.InteractionResponseData{
+			Content: "Cleared the manager role, only members with the Manage Server permission can use the bot now.",
+		}, nil
+	}
+
+	return &discordgo.InteractionResponseData{
+		Content:         fmt.Sprintf("Members with <@&%s> can now manage event channels. Allow the role to use `/%s` in the integration settings of the server so they see the command.", guild.ManagerRoleID, cmdEventChannels.Name),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}, nil
+}
+
+// Adds or removes a host role, updating every tracked discordgo.Channel right away.
+func (em *EventManager) handleConfigHostRoleCommand(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(i.GuildID).Get(&guild)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("could not find guild")
+	}
+
+	roleID := options[CommandOptionRole].RoleValue(nil, i.GuildID).ID
+	remove := options[CommandOptionRemove] != nil && options[CommandOptionRemove].BoolValue()
+
+	guild.HostRoleIDs = toggleRoleID(guild.HostRoleIDs, roleID, remove)
+	_, err = em.engine.Context(ctx).ID(guild.ID).Cols("host_role_ids").Update(&guild)
+	if err != nil {
+		return nil, err
+	}
+
+	err = em.syncGuildEventPermissions(ctx, log, s, &guild)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"role_id": roleID,
+		"remove":  remove,
+	}).Info("updated host roles")
+
+	if remove {
+		return &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("<@&%s> no longer hosts event channels.", roleID),
+		}, nil
+	}
+
+	return &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("<@&%s> now has the host permissions in every event channel.", roleID),
+	}, nil
+}
+
+// Adds or removes a staff role, updating every tracked discordgo.Channel right away.
+func (em *EventManager) handleConfigStaffRoleCommand(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(i.GuildID).Get(&guild)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("could not find guild")
+	}
+
+	roleID := options[CommandOptionRole].RoleValue(nil, i.GuildID).ID
+	remove := options[CommandOptionRemove] != nil && options[CommandOptionRemove].BoolValue()
+
+	guild.StaffRoleIDs = toggleRoleID(guild.StaffRoleIDs, roleID, remove)
+	_, err = em.engine.Context(ctx).ID(guild.ID).Cols("staff_role_ids").Update(&guild)
+	if err != nil {
+		return nil, err
+	}
+
+	err = em.syncGuildEventPermissions(ctx, log, s, &guild)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"role_id": roleID,
+		"remove":  remove,
+	}).Info("updated staff roles")
+
+	if remove {
+		return &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("<@&%s> no longer sees every event channel.", roleID),
+		}, nil
+	}
+
+	return &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("<@&%s> can now see and moderate every event channel.", roleID),
+	}, nil
+}
+
+// Changes the PermissionProfile and the participant permissions, updating every tracked discordgo.Channel right away.
+func (em *EventManager) handleConfigPermissionsCommand(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(i.GuildID).Get(&guild)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("could not find guild")
+	}
+
+	if options[CommandOptionProfile] != nil {
+		guild.PermissionProfile = options[CommandOptionProfile].StringValue()
+	}
+
+	for option, permissions := range participantPermissionOptions {
+		if options[option] == nil {
+			continue
+		}
+
+		guild.ParticipantAllow &^= permissions
+		guild.ParticipantDeny &^= permissions
+		switch options[option].StringValue() {
+		case ParticipantSettingAllow:
+			guild.ParticipantAllow |= permissions
+		case ParticipantSettingDeny:
+			guild.ParticipantDeny |= permissions
+		}
+	}
+
+	_, err = em.engine.Context(ctx).ID(guild.ID).Cols("permission_profile", "participant_allow", "participant_deny").Update(&guild)
+	if err != nil {
+		return nil, err
+	}
+
+	err = em.syncGuildEventPermissions(ctx, log, s, &guild)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"permission_profile": guild.PermissionProfile,
+		"participant_allow":  guild.ParticipantAllow,
+		"participant_deny":   guild.ParticipantDeny,
+	}).Info("updated permissions")
+
+	return &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Event channels are now %s, participants: %s.",
+			statusPermissionProfile(guild.PermissionProfile),
+			statusParticipantPermissions(guild.ParticipantAllow, guild.ParticipantDeny),
+		),
+	}, nil
+}
+
+func (em *EventManager) handleConfigPolicyCommand(ctx context.Context, log *logrus.Entry, _ *discordgo.Session, i *interactionContext, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(i.GuildID).Get(&guild)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("could not find guild")
+	}
+
+	if options[CommandOptionOnDelete] != nil {
+		guild.ChannelDeletePolicy = options[CommandOptionOnDelete].StringValue()
+	}
+	if options[CommandOptionOnEdit] != nil {
+		guild.ChannelEditPolicy = options[CommandOptionOnEdit].StringValue()
+	}
+
+	_, err = em.engine.Context(ctx).ID(guild.ID).Cols("channel_delete_policy", "channel_edit_policy").Update(&guild)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"channel_delete_policy": guild.ChannelDeletePolicy,
+		"channel_edit_policy":   guild.ChannelEditPolicy,
+	}).Info("updated channel policy")
+
+	return &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Deleted event channels: %s. Manual edits: %s.",
+			statusChannelDeletePolicy(guild.ChannelDeletePolicy),
+			statusChannelEditPolicy(guild.ChannelEditPolicy),
+		),
+	}, nil
+}
+
+// Applies the permission settings of the Guild to every tracked discordgo.Channel whose event is still scheduled.
+func (em *EventManager) syncGuildEventPermissions(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild) error {
+	var events []*Event
+	err := em.engine.Context(ctx).Where("guild_id = ? AND channel_id <> ''", guild.ID).Find(&events)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	scheduledEvents, err := s.GuildScheduledEvents(guild.ID, false)
+	if err != nil {
+		return err
+	}
+
+	scheduledEventMap := map[string]*discordgo.GuildScheduledEvent{}
+	for _, scheduledEvent := range scheduledEvents {
+		scheduledEventMap[scheduledEvent.ID] = scheduledEvent
+	}
+
+	atEveryoneRole, err := getAtEveryoneRole(s, guild.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		scheduledEvent, has := scheduledEventMap[event.ID]
+		if !has {
+			continue
+		}
+
+		channel, err := s.Channel(event.ChannelID)
+		if err != nil {
+			if isDiscordErrRESTCode(err, http.StatusNotFound) {
+				continue
+			}
+			return err
+		}
+
+		err = em.syncEventChannelPermissions(s, guild, event, scheduledEvent, channel, atEveryoneRole)
+		if err != nil {
+			log.WithError(err).WithField("channel_id", event.ChannelID).Warn("failed to update channel permissions")
+		}
+	}
+
+	return nil
+}
+
+// Points the Event at the given discordgo.Channel, making it private to the interested users.
+func (em *EventManager) linkEventChannel(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guildID string, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (string, error) {
+	query := options[CommandOptionEvent].StringValue()
+	scheduledEvent, err := findScheduledEvent(s, guildID, query)
+	if err != nil {
+		return "", err
+	}
+	if scheduledEvent == nil {
+		return fmt.Sprintf("Could not find a scheduled event matching `%s`.", query), nil
+	}
+
+	channel := options[CommandOptionChannel].ChannelValue(s)
+	if channel == nil {
+		return "Not a valid channel.", nil
+	}
+
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(guildID).Get(&guild)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("could not find guild")
+	}
+
+	var other Event
+	found, err = em.engine.Context(ctx).Where("guild_id = ? AND channel_id = ? AND id <> ?", guildID, channel.ID, scheduledEvent.ID).Get(&other)
+	if err != nil {
+		return "", err
+	}
+	if found {
+		return fmt.Sprintf("<#%s> is already linked to another event.", channel.ID), nil
+	}
+
+	event := &Event{ID: scheduledEvent.ID}
+	has, err := em.engine.Context(ctx).Get(event)
+	if err != nil {
+		return "", err
+	}
+	previousChannelID := event.ChannelID
+
+	atEveryoneRole, err := getAtEveryoneRole(s, guildID)
+	if err != nil {
+		return "", err
+	}
+
+	permissionOverwrites, err := getEventPermissionOverwrites(s, &guild, scheduledEvent, event, atEveryoneRole)
+	if err != nil {
+		return "", err
+	}
+
+	em.markOwnChannelEdit(channel.ID)
+	linked, err := s.ChannelEditComplex(channel.ID, &discordgo.ChannelEdit{
+		Name:                 eventChannelName(scheduledEvent.Name),
+		ParentID:             guild.EventChannelParentID,
+		PermissionOverwrites: permissionOverwrites,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to update linked channel: %w", err)
+	}
+
+	event.GuildID = guildID
+	event.ChannelID = channel.ID
+	event.Unlinked = false
+	event.NameLocked = false
+	event.PermissionsLocked = false
+	event.SentChannelName = eventChannelName(scheduledEvent.Name)
+	event.ChannelName = linked.Name
+	if previousChannelID != channel.ID {
+		event.InfoMessageID = ""
+	}
+	if has {
+		_, err = em.engine.Context(ctx).ID(event.ID).Cols("channel_id", "unlinked", "name_locked", "permissions_locked", "info_message_id", "sent_channel_name", "channel_name").Update(event)
+	} else {
+		_, err = em.engine.Context(ctx).Insert(event)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = em.orderEventChannels(ctx, log, s, &guild)
+	if err != nil {
+		log.WithError(err).Warn("failed to order event channels")
+	}
+
+	err = em.updateEventInfoMessage(ctx, log, s, guildID, event.ID)
+	if err != nil {
+		log.WithError(err).Warn("failed to post info message")
+	}
+
+	reply := fmt.Sprintf("Linked <#%s> to `%s`.", channel.ID, scheduledEvent.Name)
+	if previousChannelID != "" && previousChannelID != channel.ID && options[CommandOptionReleaseChannel] != nil && options[CommandOptionReleaseChannel].BoolValue() {
+		err = releaseEventChannel(s, previousChannelID, atEveryoneRole)
+		if err != nil {
+			log.WithError(err).Warn("failed to release previous channel")
+			return reply + fmt.Sprintf("\nFailed to make <#%s> public again.", previousChannelID), nil
+		}
+		reply += fmt.Sprintf("\n<#%s> is public again.", previousChannelID)
+	}
+
+	return reply, nil
+}
+
+// Stops tracking the discordgo.Channel of an Event without creating a new one.
+func (em *EventManager) unlinkEventChannel(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guildID string, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (string, error) {
+	if options[CommandOptionEvent] == nil && options[CommandOptionTrackedChannel] == nil {
+		return "Pick an event or a tracked channel to unlink.", nil
+	}
+
+	event := &Event{}
+	var found bool
+	var err error
+	var query string
+	if options[CommandOptionTrackedChannel] != nil {
+		query = options[CommandOptionTrackedChannel].StringValue()
+		found, err = em.engine.Context(ctx).Where("guild_id = ? AND channel_id = ?", guildID, query).Get(event)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			return "That channel is not linked to an event.", nil
+		}
+	} else {
+		query = options[CommandOptionEvent].StringValue()
+		found, err = em.engine.Context(ctx).Where("guild_id = ? AND id = ?", guildID, query).Get(event)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if !found {
+		scheduledEvent, err := findScheduledEvent(s, guildID, query)
+		if err != nil {
+			return "", err
+		}
+		if scheduledEvent == nil {
+			return fmt.Sprintf("Could not find a scheduled event matching `%s`.", query), nil
+		}
+
+		event = &Event{ID: scheduledEvent.ID}
+		found, err = em.engine.Context(ctx).Get(event)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if !found {
+		return "That event has no linked channel.", nil
+	}
+
+	// Holds the lock of the Event so a concurrent update or setup does not write back the channel being unlinked.
+	unlock := em.eventLocks.lock(event.ID)
+	found, err = em.engine.Context(ctx).ID(event.ID).Get(event)
+	if err != nil {
+		unlock()
+		return "", err
+	}
+	if !found || event.ChannelID == "" {
+		unlock()
+		return "That event has no linked channel.", nil
+	}
+
+	// The invite must not keep leading into a channel the bot no longer manages.
+	if event.InviteCode != "" {
+		err = deleteEventInvite(s, event.InviteCode)
+		if err != nil {
+			log.WithError(err).Warn("failed to delete invite")
+		}
+	}
+
+	if event.InfoMessageID != "" {
+		err = s.ChannelMessageDelete(event.ChannelID, event.InfoMessageID)
+		if err != nil && !isDiscordErrRESTCode(err, http.StatusNotFound) {
+			log.WithError(err).Warn("failed to delete info message")
+		}
+	}
+
+	previousChannelID := event.ChannelID
+	event.ChannelID = ""
+	event.Unlinked = true
+	event.InviteCode = ""
+	event.InviteExpiresAt = nil
+	event.InfoMessageID = ""
+	_, err = em.engine.Context(ctx).ID(event.ID).Cols("channel_id", "unlinked", "invite_code", "invite_expires_at", "info_message_id").Update(event)
+	unlock()
+	if err != nil {
+		return "", err
+	}
+
+	// Takes the lock of the Event itself.
+	if len(event.VoiceAccessIDs) > 0 {
+		err = em.revokeAllVoiceAccess(ctx, s, event)
+		if err != nil {
+			log.WithError(err).Warn("failed to revoke voice access")
+		}
+	}
+
+	reply := fmt.Sprintf("Unlinked <#%s>.", previousChannelID)
+	if options[CommandOptionReleaseChannel] != nil && options[CommandOptionReleaseChannel].BoolValue() {
+		atEveryoneRole, err := getAtEveryoneRole(s, guildID)
+		if err != nil {
+			return "", err
+		}
+
+		err = releaseEventChannel(s, previousChannelID, atEveryoneRole)
+		if err != nil {
+			log.WithError(err).Warn("failed to release channel")
+			return reply + " Failed to make it public again.", nil
+		}
+		reply += " It is public again."
+	}
+
+	return reply, nil
+}
+
+// Removes the @everyone deny so the discordgo.Channel is visible to the whole discordgo.Guild.
+func releaseEventChannel(s *discordgo.Session, channelID string, atEveryoneRole *discordgo.Role) error {
+	err := s.ChannelPermissionDelete(channelID, atEveryoneRole.ID)
+	if err != nil && !isDiscordErrRESTCode(err, http.StatusNotFound) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// componentID is what we encode into the CustomID of every message component and modal we send, so a click can be
+// routed to its handler and traced back to the wizard session and user it was created for.
+type componentID struct {
+	Namespace string
+	SessionID string
+	UserID    string
+	Action    string
+	Arg       string
+}
+
+type componentHandler func(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, id componentID) error
+
+const componentIDSeparator = ":"
+
+// Length of the truncated HMAC, short enough to keep CustomIDs under Discord's 100 character limit.
+const componentSignatureLength = 6
+
+var errInvalidComponentID = errors.New("invalid component id")
+
+// Namespaces of components on messages that outlive a restart, such as announcements and direct messages. They are
+// not signed, as the secret may be generated at startup, so their handlers must only act for whoever pressed them
+// after checking their access.
+var persistentComponentNamespaces = map[string]bool{
+	membershipComponentNamespace: true,
+	onboardingComponentNamespace: true,
+}
+
+func (id componentID) payload() string {
+	return strings.Join([]string{id.Namespace, id.SessionID, id.UserID, id.Action, id.Arg}, componentIDSeparator)
+}
+
+func (em *EventManager) signComponentPayload(payload string) string {
+	mac := hmac.New(sha256.New, em.interactionSecret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:componentSignatureLength])
+}
+
+func (em *EventManager) encodeComponentID(id componentID) string {
+	payload := id.payload()
+	if persistentComponentNamespaces[id.Namespace] {
+		return payload + componentIDSeparator
+	}
+
+	return payload + componentIDSeparator + em.signComponentPayload(payload)
+}
+
+func (em *EventManager) decodeComponentID(customID string) (componentID, error) {
+	parts := strings.Split(customID, componentIDSeparator)
+	if len(parts) != 6 {
+		return componentID{}, errInvalidComponentID
+	}
+
+	id := componentID{
+		Namespace: parts[0],
+		SessionID: parts[1],
+		UserID:    parts[2],
+		Action:    parts[3],
+		Arg:       parts[4],
+	}
+
+	if persistentComponentNamespaces[id.Namespace] {
+		return id, nil
+	}
+
+	expected := em.signComponentPayload(id.payload())
+	if !hmac.Equal([]byte(expected), []byte(parts[5])) {
+		return componentID{}, errInvalidComponentID
+	}
+
+	return id, nil
+}
+
+// Verifies the CustomID of a component or modal interaction and hands it to the handler registered for its
+// namespace, refusing anyone but the user the component was created for.
+func (em *EventManager) routeComponent(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, customID string) error {
+	id, err := em.decodeComponentID(customID)
+	if err != nil {
+		log.WithField("custom_id", customID).Warn("rejected component")
+		return i.respond(&discordgo.InteractionResponseData{
+			Content: "This message is no longer valid, please run the command again.",
+		})
+	}
+
+	log = log.WithFields(logrus.Fields{
+		"component_namespace": id.Namespace,
+		"component_action":    id.Action,
+		"session_id":          id.SessionID,
+	})
+
+	if id.UserID != "" && id.UserID != i.userID() {
+		log.Warn("component used by someone other than its initiator")
+		return i.respond(&discordgo.InteractionResponseData{
+			Content: "Only the person who started this can use it.",
+		})
+	}
+
+	handler, has := em.componentHandlers[id.Namespace]
+	if !has {
+		return fmt.Errorf("no component handler for namespace %q", id.Namespace)
+	}
+
+	return handler(ctx, log, s, i, id)
+}
@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+const testUserID = "100000000000000004"
+
+func TestComponentIDRoundTrip(t *testing.T) {
+	em := newTestEventManager()
+
+	tests := []struct {
+		name string
+		id   componentID
+	}{
+		{"signed", componentID{Namespace: syncComponentNamespace, SessionID: "abcdef0123456789", UserID: testUserID, Action: SyncActionNext, Arg: "2"}},
+		{"signed without user", componentID{Namespace: setupComponentNamespace, SessionID: testGuildID, Action: SetupActionKeep, Arg: SetupStepCategory}},
+		{"persistent", componentID{Namespace: membershipComponentNamespace, SessionID: testGuildID, Action: MembershipActionJoin}},
+		{"persistent with user", componentID{Namespace: onboardingComponentNamespace, SessionID: testGuildID, UserID: testUserID, Action: OnboardingActionMute}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, err := em.decodeComponentID(em.encodeComponentID(test.id))
+			if err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+			if id != test.id {
+				t.Errorf("got %+v, want %+v", id, test.id)
+			}
+		})
+	}
+}
+
+func TestDecodeComponentIDRejectsTampering(t *testing.T) {
+	em := newTestEventManager()
+	signed := em.encodeComponentID(componentID{Namespace: syncComponentNamespace, SessionID: "abcdef0123456789", UserID: testUserID, Action: SyncActionNext, Arg: "2"})
+	other := NewEventManager(nil, nil, EventManagerConfig{InteractionSecret: "other"})
+
+	tests := []struct {
+		name     string
+		customID string
+	}{
+		{"changed action", strings.Replace(signed, ":"+SyncActionNext+":", ":"+SyncActionDone+":", 1)},
+		{"changed user", strings.Replace(signed, testUserID, "100000000000000005", 1)},
+		{"missing signature", signed[:strings.LastIndex(signed, componentIDSeparator)+1]},
+		{"other secret", other.encodeComponentID(componentID{Namespace: syncComponentNamespace, SessionID: "abcdef0123456789", UserID: testUserID, Action: SyncActionNext, Arg: "2"})},
+		{"too few parts", "sync:abcdef0123456789:next"},
+		{"too many parts", signed + ":extra"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := em.decodeComponentID(test.customID)
+			if err != errInvalidComponentID {
+				t.Errorf("got %v, want %v", err, errInvalidComponentID)
+			}
+		})
+	}
+}
+
+func TestComponentIDLength(t *testing.T) {
+	em := newTestEventManager()
+
+	// Snowflakes take up to 20 digits.
+	const snowflake = "18446744073709551615"
+	session := &WizardSession{ID: newSessionID(), UserID: snowflake}
+
+	tests := []struct {
+		name     string
+		customID string
+	}{
+		{"sync", em.syncCustomID(session, SyncActionSearch, snowflake)},
+		{"setup", em.setupCustomID(snowflake, snowflake, SetupStepDeleteWhenDone, SetupActionCreate)},
+		{"membership", em.membershipCustomID(snowflake, MembershipActionLeave)},
+		{"onboarding", em.onboardingCustomID(snowflake, snowflake, OnboardingActionSetup)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if len(test.customID) > 100 {
+				t.Errorf("%q is %d characters long, Discord allows 100", test.customID, len(test.customID))
+			}
+		})
+	}
+}
+
+func TestRouteComponentRejectsOtherUser(t *testing.T) {
+	em := newTestEventManager()
+
+	var handled bool
+	em.componentHandlers[syncComponentNamespace] = func(context.Context, *logrus.Entry, *discordgo.Session, *interactionContext, componentID) error {
+		handled = true
+		return nil
+	}
+
+	var response discordgo.InteractionResponse
+	s := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &response)
+		w.WriteHeader(http.StatusNoContent)
+	}, 0)
+
+	customID := em.encodeComponentID(componentID{Namespace: syncComponentNamespace, SessionID: "abcdef0123456789", UserID: testUserID, Action: SyncActionNext})
+
+	tests := []struct {
+		name    string
+		userID  string
+		handled bool
+	}{
+		{"initiator", testUserID, true},
+		{"someone else", "100000000000000005", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handled = false
+			response = discordgo.InteractionResponse{}
+
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			log := logrus.NewEntry(logger)
+			i := newInteractionContext(s, log, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+				ID:     "100000000000000006",
+				Token:  "token",
+				Type:   discordgo.InteractionMessageComponent,
+				Member: &discordgo.Member{User: &discordgo.User{ID: test.userID}},
+			}})
+
+			err := em.routeComponent(context.Background(), log, s, i, customID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if handled != test.handled {
+				t.Errorf("handled = %v, want %v", handled, test.handled)
+			}
+			if !test.handled && (response.Data == nil || !strings.Contains(response.Data.Content, "Only the person")) {
+				t.Errorf("expected a refusal, got %+v", response.Data)
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@ package bot
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -12,20 +14,61 @@ import (
 	"xorm.io/xorm/names"
 )
 
+type EventManagerConfig struct {
+	// Signs the CustomIDs of the components we send, a random secret is used when empty which invalidates
+	// components sent before a restart.
+	InteractionSecret string
+	// Registers the commands to this discordgo.Guild only, so changes show up instantly while developing.
+	DevGuildID string
+}
+
 type EventManager struct {
 	logger *logrus.Logger
 	engine xorm.EngineInterface
+
+	interactionSecret []byte
+	componentHandlers map[string]componentHandler
+	commands          *commandRegistry
+	devGuildID        string
+
+	scheduledEvents *ttlCache[[]*discordgo.GuildScheduledEvent]
+	channelUpdates  *coalescer
+	eventLocks      *keyedMutex
+	ownEdits        *ttlCache[bool]
 }
 
 func NewEventManager(
 	logger *logrus.Logger,
 	engine xorm.EngineInterface,
+	cfg EventManagerConfig,
 ) *EventManager {
+	interactionSecret := []byte(cfg.InteractionSecret)
+	if len(interactionSecret) == 0 {
+		interactionSecret = make([]byte, 32)
+		_, _ = rand.Read(interactionSecret)
+	}
+
 	em := &EventManager{
 		logger: logger,
 		engine: engine,
+
+		interactionSecret: interactionSecret,
+		devGuildID:        cfg.DevGuildID,
+
+		scheduledEvents: newTTLCache[[]*discordgo.GuildScheduledEvent](30 * time.Second),
+		channelUpdates:  newCoalescer(channelUpdateDelay),
+		eventLocks:      newKeyedMutex(),
+		ownEdits:        newTTLCache[bool](ownChannelEditWindow),
 	}
 
+	em.componentHandlers = map[string]componentHandler{
+		setupComponentNamespace:      em.handleSetupComponent,
+		syncComponentNamespace:       em.handleSyncComponent,
+		onboardingComponentNamespace: em.handleOnboardingComponent,
+		membershipComponentNamespace: em.handleMembershipComponent,
+	}
+	em.commands = em.newCommandRegistry()
+
 	return em
 }
 
@@ -38,6 +81,15 @@ func (em EventManager) SyncDB() error {
 	if err := em.engine.Sync2(new(Event)); err != nil {
 		return err
 	}
+	if err := em.engine.Sync2(new(WizardSession)); err != nil {
+		return err
+	}
+	if err := em.engine.Sync2(new(EventSetup)); err != nil {
+		return err
+	}
+	if err := em.engine.Sync2(new(CommandRegistration)); err != nil {
+		return err
+	}
 	em.engine.ShowSQL(true)
 
 	return nil
@@ -66,7 +118,7 @@ func (em *EventManager) ConsumeSession(s *discordgo.Session) {
 
 		log.Debug("received")
 
-		err := em.onGuildCreate(context.TODO(), s, m)
+		err := em.onGuildCreate(context.TODO(), log, s, m)
 		if err != nil {
 			log.WithError(err).Error("failed guild create")
 			return
@@ -75,7 +127,7 @@ func (em *EventManager) ConsumeSession(s *discordgo.Session) {
 
 	s.AddHandler(func(s *discordgo.Session, m *discordgo.GuildDelete) {
 		log := em.logger.WithFields(logrus.Fields{
-			"method":   "GuildCreate",
+			"method":   "GuildDelete",
 			"guild_id": m.ID,
 		})
 
@@ -88,6 +140,55 @@ func (em *EventManager) ConsumeSession(s *discordgo.Session) {
 		}
 	})
 
+	s.AddHandler(func(s *discordgo.Session, m *discordgo.ChannelDelete) {
+		log := em.logger.WithFields(logrus.Fields{
+			"method":     "ChannelDelete",
+			"guild_id":   m.GuildID,
+			"channel_id": m.ID,
+		})
+
+		log.Debug("received")
+
+		err := em.onChannelDelete(context.TODO(), log, s, m)
+		if err != nil {
+			log.WithError(err).Error("failed")
+			return
+		}
+	})
+
+	s.AddHandler(func(s *discordgo.Session, m *discordgo.ChannelUpdate) {
+		log := em.logger.WithFields(logrus.Fields{
+			"method":     "ChannelUpdate",
+			"guild_id":   m.GuildID,
+			"channel_id": m.ID,
+		})
+
+		log.Debug("received")
+
+		err := em.onChannelUpdate(context.TODO(), log, s, m)
+		if err != nil {
+			log.WithError(err).Error("failed")
+			return
+		}
+	})
+
+	s.AddHandler(func(s *discordgo.Session, m *discordgo.StageInstanceEventCreate) {
+		log := em.logger.WithFields(logrus.Fields{
+			"method":     "StageInstanceCreate",
+			"guild_id":   m.GuildID,
+			"channel_id": m.ChannelID,
+			"event_id":   m.GuildScheduledEventID,
+		})
+
+		log.Debug("received")
+
+		err := em.onStageInstanceCreate(context.TODO(), log, s, m)
+		if err != nil {
+			log.WithError(err).Error("failed")
+			return
+		}
+	})
+
 	s.AddHandler(func(s *discordgo.Session, m *discordgo.GuildScheduledEventCreate) {
 		log := em.logger.WithFields(logrus.Fields{
 			"method":   "GuildScheduledEventCreate",
@@ -97,6 +198,8 @@ func (em *EventManager) ConsumeSession(s *discordgo.Session) {
 
 		log.Debug("received")
 
+		em.scheduledEvents.invalidate(m.GuildID)
+
 		err := em.onGuildEventCreate(context.TODO(), log, s, m)
 		if err != nil {
 			log.WithError(err).Error("failed")
@@ -113,6 +216,8 @@ func (em *EventManager) ConsumeSession(s *discordgo.Session) {
 
 		log.Debug("received")
 
+		em.scheduledEvents.invalidate(m.GuildID)
+
 		err := em.onGuildEventUpdate(context.TODO(), log, s, m)
 		if err != nil {
 			log.WithError(err).Error("failed")
@@ -129,6 +234,8 @@ func (em *EventManager) ConsumeSession(s *discordgo.Session) {
 
 		log.Debug("received")
 
+		em.scheduledEvents.invalidate(m.GuildID)
+
 		err := em.onGuildEventDelete(context.TODO(), log, s, m)
 		if err != nil {
 			log.WithError(err).Error("failed")
@@ -188,23 +295,10 @@ func (em *EventManager) ConsumeSession(s *discordgo.Session) {
 
 		log.Debug("received")
 
-		err := em.handleInteraction(context.TODO(), log, s, i)
-		if err != nil {
-			log.WithError(err).Error("failed")
-			return
-		}
+		em.handleInteraction(context.TODO(), log, s, i)
 	})
 }
 
-func (em *EventManager) RegisterGlobalCommands(session *discordgo.Session) error {
-	_, err := session.ApplicationCommandBulkOverwrite(session.State.User.ID, "", globalCommands)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func (em *EventManager) reconcile(ctx context.Context, log *logrus.Entry, session *discordgo.Session, guild *discordgo.Guild) error {
 	var internalGuild Guild
 	found, err := em.engine.Context(ctx).Table(&Guild{}).Where("id = ?", guild.ID).Get(&internalGuild)
@@ -225,6 +319,11 @@ func (em *EventManager) reconcile(ctx context.Context, log *logrus.Entry, sessio
 		return nil
 	}
 
+	err = em.resumeEventSetups(ctx, log, session, guild.ID)
+	if err != nil {
+		return fmt.Errorf("failed to resume event channel setups: %w", err)
+	}
+
 	var internalEvents []*Event
 	err = em.engine.Context(ctx).Table(&Event{}).Where("guild_id = ?", guild.ID).Find(&internalEvents)
 	if err != nil {
@@ -251,11 +350,31 @@ func (em *EventManager) reconcile(ctx context.Context, log *logrus.Entry, sessio
 		channelNameMap[channels[i].Name] = channels[i]
 	}
 
+	// Channels deleted while we were not listening.
+	for _, channelID := range []string{internalGuild.EventAnnouncementChannelID, internalGuild.EventChannelParentID} {
+		if _, has := channelIDMap[channelID]; channelID != "" && !has {
+			err = em.onConfiguredChannelDeleted(ctx, log, session, &internalGuild, channelID, "")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, _, err = em.preflight(ctx, log, session, &internalGuild)
+	if err != nil {
+		return err
+	}
+
 	events, err := session.GuildScheduledEvents(guild.ID, false)
 	if err != nil {
 		return err
 	}
 
+	atEveryoneRole, err := getAtEveryoneRole(session, guild.ID)
+	if err != nil {
+		return err
+	}
+
 	for _, event := range events {
 		internalEvent, has := internalEventsMap[event.ID]
 		log := em.logger.WithFields(logrus.Fields{
@@ -267,6 +386,10 @@ func (em *EventManager) reconcile(ctx context.Context, log *logrus.Entry, sessio
 		// Remove all found or created keys to see what was deleted.
 		delete(internalEventsMap, event.ID)
 
+		if has && internalEvent.Unlinked {
+			continue
+		}
+
 		if has {
 			if internalEvent.ChannelID == "" {
 				log.Warnf("found internal event with missing ChannelID")
@@ -293,23 +416,48 @@ func (em *EventManager) reconcile(ctx context.Context, log *logrus.Entry, sessio
 		channel, err := session.Channel(internalEvent.ChannelID)
 		if err != nil {
 			if isDiscordErrRESTCode(err, http.StatusNotFound) {
+				err = em.replaceDeletedEventChannel(ctx, log, session, &internalGuild, internalEvent, event)
+				if err != nil {
+					log.WithError(err).Error("failed to replace deleted event channel")
+				}
 				continue
 			}
 			return err
 		}
 
-		if event.Name != channel.Name {
-			_, err = session.ChannelEdit(internalEvent.ChannelID, eventChannelName(event.Name))
+		if edit := getEventChannelEdit(&internalGuild, internalEvent, event, channel); edit != nil {
+			_, err = em.editEventChannel(ctx, session, internalEvent, edit)
 			if err != nil {
-				return fmt.Errorf("failed to update channel name: %w", err)
+				return fmt.Errorf("failed to update channel: %w", err)
 			}
 		}
+
+		err = em.syncEventChannelPermissions(session, &internalGuild, internalEvent, event, channel, atEveryoneRole)
+		if err != nil {
+			return fmt.Errorf("failed to update channel permissions: %w", err)
+		}
+
+		err = em.syncEventVoiceAccess(ctx, session, &internalGuild, internalEvent, event)
+		if err != nil {
+			log.WithError(err).Warn("failed to update voice access")
+		}
+
+		em.scheduleEventInfoUpdate(log, session, guild.ID, event.ID)
 	}
 
 	for _, event := range internalEventsMap {
-		_, err = session.ChannelDelete(event.ChannelID)
-		if err != nil && !isDiscordErrRESTCode(err, http.StatusNotFound) {
-			return err
+		if event.InviteCode != "" {
+			err = deleteEventInvite(session, event.InviteCode)
+			if err != nil {
+				log.WithError(err).Warn("failed to delete invite")
+			}
+		}
+
+		if event.ChannelID != "" {
+			_, err = session.ChannelDelete(event.ChannelID)
+			if err != nil && !isDiscordErrRESTCode(err, http.StatusNotFound) {
+				return err
+			}
 		}
 
 		_, err = em.engine.Delete(event)
@@ -318,12 +466,22 @@ func (em *EventManager) reconcile(ctx context.Context, log *logrus.Entry, sessio
 		}
 	}
 
+	err = em.collapseOverflowCategories(ctx, log, session, &internalGuild)
+	if err != nil {
+		log.WithError(err).Warn("failed to collapse overflow categories")
+	}
+
+	err = em.orderEventChannels(ctx, log, session, &internalGuild)
+	if err != nil {
+		log.WithError(err).Warn("failed to order event channels")
+	}
+
 	return nil
 }
 
 // Ensures we reconcile all discordgo.Guild after a restart.
 func (em *EventManager) onReady(ctx context.Context, log *logrus.Entry, s *discordgo.Session, r *discordgo.Ready) error {
-	err := em.RegisterGlobalCommands(s)
+	err := em.RegisterCommands(ctx, log, s)
 	if err != nil {
 		return err
 	}
@@ -339,29 +497,26 @@ func (em *EventManager) onReady(ctx context.Context, log *logrus.Entry, s *disco
 	return nil
 }
 
-// When a discordgo.Guild is added we want to alert the Owner that they need to run the config command.
-func (em *EventManager) onGuildCreate(ctx context.Context, s *discordgo.Session, m *discordgo.GuildCreate) error {
-	guild, exists, err := em.possiblyCreateGuild(ctx, m.Guild)
+// When a discordgo.Guild is added we want to explain the Owner how to get started.
+func (em *EventManager) onGuildCreate(ctx context.Context, log *logrus.Entry, s *discordgo.Session, m *discordgo.GuildCreate) error {
+	restored, err := em.restoreGuild(ctx, log, m.Guild.ID)
 	if err != nil {
-		return fmt.Errorf("failed to create guild: %w", err)
+		return fmt.Errorf("failed to restore guild: %w", err)
 	}
 
-	// We don't want to reconcile if we already have the Guild recorded as this is just an update.
-	if exists {
-		return nil
+	// Events may have changed while we were gone.
+	if restored {
+		return em.reconcile(ctx, log, s, m.Guild)
 	}
 
-	// First time we have seen the guild, we want to go ahead and create the welcome message.
-	channel, err := s.UserChannelCreate(m.OwnerID)
+	guild, exists, err := em.possiblyCreateGuild(ctx, m.Guild)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create guild: %w", err)
 	}
 
-	_, err = s.ChannelMessageSend(channel.ID,
-		"Thanks for adding Event Channels to your Discord server!\nDon't forget to run the setup command!",
-	)
-	if err != nil {
-		return err
+	// We don't want to reconcile if we already have the Guild recorded as this is just an update.
+	if exists {
+		return nil
 	}
 
 	events, err := s.GuildScheduledEvents(m.Guild.ID, false)
@@ -378,137 +533,115 @@ func (em *EventManager) onGuildCreate(ctx context.Context, s *discordgo.Session,
 		}
 	}
 
+	// First time we have seen the guild, we want to go ahead and create the welcome message. The background tasks
+	// try again later when it can't be delivered anywhere.
+	err = em.sendOnboarding(ctx, s, guild, m.Guild, false)
+	if err != nil {
+		log.WithError(err).Warn("failed to send onboarding")
+	}
+
 	return nil
 }
 
-// When a discordgo.Guild is removed, we drop its data.
+// When a discordgo.Guild is removed, we keep its data for a while in case the bot is added back.
 func (em *EventManager) onGuildDelete(ctx context.Context, log *logrus.Entry, s *discordgo.Session, m *discordgo.GuildDelete) error {
-	_, err := em.engine.Context(ctx).Delete(&Guild{ID: m.Guild.ID})
-	if err != nil {
-		log.WithError(err).Warn("failed to delete the guild")
+	// Discord also sends these during outages, the guild comes back with a GuildCreate once it is available again.
+	if m.Unavailable {
+		log.Info("guild unavailable")
+		return nil
 	}
 
-	_, err = em.engine.Context(ctx).Delete(&Event{GuildID: m.Guild.ID})
+	_, err := em.engine.Context(ctx).ID(m.Guild.ID).Delete(&Guild{})
 	if err != nil {
-		log.WithError(err).Warn("failed to delete the guild")
+		return fmt.Errorf("failed to mark the guild as deleted: %w", err)
 	}
 
+	log.Info("guild removed, keeping its data for the retention window")
+
 	return nil
 }
 
 // Create a discordgo.Channel for the event then put a discordgo.Message in the
-// discordgo.Guild's specified EventAnnouncement discordgo.Channel.
-func (em *EventManager) onGuildEventCreate(ctx context.Context, log *logrus.Entry, s *discordgo.Session, m *discordgo.GuildScheduledEventCreate) (err error) {
-	var channel *discordgo.Channel
-	var message *discordgo.Message
-	var event *Event
-
+// discordgo.Guild's specified EventAnnouncement discordgo.Channel. Every step is recorded in an EventSetup, so a
+// failure undoes exactly what was done.
+func (em *EventManager) onGuildEventCreate(ctx context.Context, log *logrus.Entry, s *discordgo.Session, m *discordgo.GuildScheduledEventCreate) error {
 	var guild Guild
-	_, err = em.engine.Context(ctx).ID(m.GuildID).Get(&guild)
+	_, err := em.engine.Context(ctx).ID(m.GuildID).Get(&guild)
 	if err != nil {
 		return fmt.Errorf("failed to find internal guild: %w", err)
 	}
 
-	// Try to clean up our mess if anything failed.
-	defer func() {
-		if err == nil {
-			return
-		}
-
-		if channel != nil {
-			if _, err := s.ChannelDelete(channel.ID); err != nil {
-				log.WithError(err).Errorf("failed to cleanup channel %q", channel.ID)
-			}
-		}
-		if message != nil {
-			if err := s.ChannelMessageDelete(guild.ID, message.ID); err != nil {
-				log.WithError(err).Errorf("failed to cleanup announce message %q", message.ID)
-			}
-		}
-		if event != nil {
-			if _, err := em.engine.Table(&Event{}).ID(m.ID).Delete(); err != nil {
-				log.WithError(err).Errorf("failed to cleanup internal event")
-			}
-		}
-	}()
-
-	var roles []*discordgo.Role
-	roles, err = s.GuildRoles(m.GuildID)
+	// Creating the channel would fail halfway through, leaving the owner wondering why nothing happened.
+	problems, blocking, err := em.preflight(ctx, log, s, &guild)
 	if err != nil {
-		return fmt.Errorf("failed to create role: %w", err)
+		return err
 	}
-	var atEveryoneRole *discordgo.Role
-	for _, role := range roles {
-		if role.Name == "@everyone" {
-			atEveryoneRole = role
-			break
-		}
+	if blocking {
+		return fmt.Errorf("missing permissions: %s", strings.Join(problems, "; "))
 	}
-	if atEveryoneRole == nil {
-		return fmt.Errorf("failed to find @everyone role")
-	}
-
-	channel, err = s.GuildChannelCreateComplex(m.GuildID, discordgo.GuildChannelCreateData{
-		Name:     eventChannelName(m.Name),
-		Type:     discordgo.ChannelTypeGuildText,
-		Topic:    m.Description,
-		ParentID: guild.EventChannelParentID,
-		PermissionOverwrites: []*discordgo.PermissionOverwrite{
-			{
-				ID:    s.State.User.ID,
-				Type:  discordgo.PermissionOverwriteTypeMember,
-				Allow: discordgo.PermissionViewChannel,
-			},
-			{
-				ID:   atEveryoneRole.ID,
-				Type: discordgo.PermissionOverwriteTypeRole,
-				Deny: discordgo.PermissionViewChannel,
-			},
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create channel: %w", err)
+
+	event, err := em.setupEvent(ctx, log, s, &guild, m.GuildScheduledEvent)
+	if err != nil || event == nil {
+		return err
 	}
 
-	if guild.EventAnnouncementChannelID != "" {
-		invite, err := s.ChannelInviteCreate(channel.ID, discordgo.Invite{
-			MaxAge:    0,
-			MaxUses:   0,
-			Temporary: false,
-			Unique:    false,
-		})
-		if err != nil {
-			return err
-		}
+	if err := em.syncEventVoiceAccess(ctx, s, &guild, event, m.GuildScheduledEvent); err != nil {
+		log.WithError(err).Warn("failed to grant voice access")
+	}
 
-		message, err = s.ChannelMessageSend(guild.EventAnnouncementChannelID, guild.GetNewEventChannelMessage(m.Name, invite.Code, m.ID))
-		if err != nil {
-			return fmt.Errorf("failed to announce channel: %w", err)
-		}
+	if err := em.orderEventChannels(ctx, log, s, &guild); err != nil {
+		log.WithError(err).Warn("failed to order event channels")
+	}
+
+	return nil
+}
+
+// Creates the channel of the discordgo.GuildScheduledEvent and the Event for it, nil when it already exists. The
+// lock of the event keeps resumeEventSetups from working on the same EventSetup meanwhile.
+func (em *EventManager) setupEvent(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild, scheduledEvent *discordgo.GuildScheduledEvent) (*Event, error) {
+	unlock := em.eventLocks.lock(scheduledEvent.ID)
+	defer unlock()
 
-		message, err = s.ChannelMessageSend(channel.ID, getEventInviteURL(invite.Code, m.ID))
+	exists, err := em.engine.Context(ctx).Exist(&Event{ID: scheduledEvent.ID})
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, nil
+	}
+
+	setup := &EventSetup{ID: scheduledEvent.ID}
+	found, err := em.engine.Context(ctx).Get(setup)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		// An earlier attempt was interrupted, start over from a clean slate.
+		err = em.rollbackEventSetup(ctx, log, s, setup)
 		if err != nil {
-			return fmt.Errorf("failed to announce channel: %w", err)
+			return nil, fmt.Errorf("failed to roll back earlier setup: %w", err)
 		}
 	}
 
-	event = &Event{
-		ID:        m.ID,
-		GuildID:   m.GuildID,
-		ChannelID: channel.ID,
-	}
-	if message != nil {
-		event.AnnounceMessageID = &message.ID
+	setup = &EventSetup{ID: scheduledEvent.ID, GuildID: scheduledEvent.GuildID}
+	_, err = em.engine.Context(ctx).Insert(setup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record setup: %w", err)
 	}
-	_, err = em.engine.Insert(event)
+
+	event, err := em.runEventSetup(ctx, log, s, guild, setup, scheduledEvent)
 	if err != nil {
-		return fmt.Errorf("failed to insert event: %w", err)
+		if err := em.rollbackEventSetup(ctx, log, s, setup); err != nil {
+			log.WithError(err).Error("failed to roll back event channel setup")
+		}
+		return nil, err
 	}
 
-	return nil
+	return event, nil
 }
 
-// Check to see if the discordgo.GuildScheduledEvent ended and if so, remove it, otherwise update the name if it changed.
+// Check to see if the discordgo.GuildScheduledEvent ended and if so, remove it, otherwise update the channel once the
+// updates settle.
 func (em *EventManager) onGuildEventUpdate(ctx context.Context, log *logrus.Entry, s *discordgo.Session, m *discordgo.GuildScheduledEventUpdate) error {
 	guild, event, err := em.getGuildAndEvent(ctx, m.GuildScheduledEvent.GuildID, m.GuildScheduledEvent.ID)
 	if err != nil {
@@ -525,10 +658,11 @@ func (em *EventManager) onGuildEventUpdate(ctx context.Context, log *logrus.Entr
 
 		return em.deleteEvent(ctx, log, s, guild, event)
 	default:
-		_, err = s.ChannelEdit(event.ChannelID, eventChannelName(m.Name))
-		if err != nil {
-			return fmt.Errorf("failed to update channel name: %w", err)
+		if event.ChannelID == "" {
+			return nil
 		}
+
+		em.scheduleEventChannelUpdate(log, s, m.GuildID, m.ID)
 	}
 
 	return nil
@@ -559,10 +693,11 @@ func (em *EventManager) onGuildEventUserAdd(ctx context.Context, log *logrus.Ent
 		return nil
 	}
 
+	var guild *Guild
 	var event *Event
 	var err error
 	for i := 0; i < 5; i++ {
-		_, event, err = em.getGuildAndEvent(ctx, m.GuildID, m.GuildScheduledEventID)
+		guild, event, err = em.getGuildAndEvent(ctx, m.GuildID, m.GuildScheduledEventID)
 		if err != nil {
 			return err
 		}
@@ -573,16 +708,33 @@ func (em *EventManager) onGuildEventUserAdd(ctx context.Context, log *logrus.Ent
 		time.Sleep(1 * time.Second)
 	}
 
-	if event == nil {
+	if event == nil || event.ChannelID == "" {
 		log.Warn("was not able to find internal event")
 		return nil
 	}
 
-	err = s.ChannelPermissionSet(event.ChannelID, m.UserID, discordgo.PermissionOverwriteTypeMember, discordgo.PermissionViewChannel, 0)
+	em.scheduleEventInfoUpdate(log, s, m.GuildID, m.GuildScheduledEventID)
+
+	scheduledEvent, err := s.GuildScheduledEvent(m.GuildID, m.GuildScheduledEventID, false)
+	if err != nil {
+		return fmt.Errorf("failed to get scheduled event: %w", err)
+	}
+
+	allow, deny := getParticipantPermissions(guild)
+	if isEventHost(scheduledEvent, event.CohostIDs, m.UserID) {
+		allow, deny = getHostPermissions(guild), 0
+	}
+
+	err = em.setEventMemberPermissions(s, guild, event.ChannelID, m.UserID, allow, deny)
 	if err != nil {
 		return fmt.Errorf("failed to add permissions to channel: %w", err)
 	}
 
+	err = em.grantVoiceAccess(ctx, s, guild, event, scheduledEvent, m.UserID)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -596,11 +748,30 @@ func (em *EventManager) onGuildEventUserRemove(ctx context.Context, log *logrus.
 		return err
 	}
 
-	if event == nil {
+	if event == nil || event.ChannelID == "" {
 		log.Warn("was not able to find internal event")
 		return nil
 	}
 
+	em.scheduleEventInfoUpdate(log, s, m.GuildID, m.GuildScheduledEventID)
+
+	scheduledEvent, err := s.GuildScheduledEvent(m.GuildID, m.GuildScheduledEventID, false)
+	if err != nil {
+		return fmt.Errorf("failed to get scheduled event: %w", err)
+	}
+
+	err = em.revokeVoiceAccess(ctx, s, event, m.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Hosts keep access to their channel whether they are interested or not, as do members who joined with the
+	// button until they leave with it.
+	if isEventHost(scheduledEvent, event.CohostIDs, m.UserID) || containsString(event.MemberIDs, m.UserID) {
+		return nil
+	}
+
+	em.markOwnChannelEdit(event.ChannelID)
 	err = s.ChannelPermissionDelete(event.ChannelID, m.UserID)
 	if err != nil {
 		return fmt.Errorf("failed to remove permissions for channel: %w", err)
@@ -609,309 +780,47 @@ func (em *EventManager) onGuildEventUserRemove(ctx context.Context, log *logrus.
 	return nil
 }
 
-func (em *EventManager) handleInteraction(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *discordgo.InteractionCreate) (err error) {
-	defer func() {
-		if err != nil {
-			_, _ = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-				Content: "Something went wrong",
-			})
-			return
+// Wraps the discordgo.InteractionCreate in an interactionContext, routes it and reports any error to the user.
+func (em *EventManager) handleInteraction(ctx context.Context, log *logrus.Entry, s *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	i := newInteractionContext(s, log, interaction)
+	if i.Type == discordgo.InteractionPing {
+		return
+	}
+	if i.Type != discordgo.InteractionApplicationCommandAutocomplete {
+		i.startAutoDefer()
+		defer i.stopAutoDefer()
+	}
+
+	allowed, err := em.authorizeInteraction(ctx, s, i)
+	if err == nil {
+		if allowed {
+			err = em.routeInteraction(ctx, log, s, i)
+		} else {
+			err = denyInteraction(log, i)
 		}
-	}()
+	}
+	if err != nil {
+		i.reportError(err)
+	}
+}
 
+func (em *EventManager) routeInteraction(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext) error {
 	switch i.Type {
-	case discordgo.InteractionPing:
 	case discordgo.InteractionApplicationCommand:
-		switch i.ApplicationCommandData().Name {
-		case cmdOptions.Name:
-			optionsSlice := i.ApplicationCommandData().Options
-			options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(optionsSlice))
-			for _, opt := range optionsSlice {
-				options[opt.Name] = opt
-			}
-
-			var guild Guild
-			found, err := em.engine.Context(ctx).ID(i.GuildID).Get(&guild)
-			if err != nil {
-				return err
-			}
-
-			if !found {
-				return fmt.Errorf("could not find guild")
-			}
-
-			var reply string
-			errMessage := getConfigOptionsMap(s, options, &guild)
-			if errMessage != "" {
-				reply = errMessage
-				break
-			}
-
-			guild.ConfigurationWasRun = true
-			_, err = em.engine.ID(i.GuildID).UseBool().Update(guild)
-			if err != nil {
-				em.logger.WithError(err).Error("failed to update guild message")
-				reply = "Failed to update config settings."
-			} else {
-				reply = "Successfully updated config settings!"
-			}
-
-			err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: reply,
-				},
-			})
-			if err != nil {
-				return fmt.Errorf("failed to reply to command: %w", err)
-			}
-		case cmdSync.Name:
-			err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "loading your events",
-				},
-			})
-			if err != nil {
-				return err
-			}
-
-			selects, err := em.getEventSelects(s, i.GuildID, false)
-			if err != nil {
-				return err
-			}
-
-			var webhookParams *discordgo.WebhookParams
-			if len(selects) == 0 {
-				webhookParams = &discordgo.WebhookParams{
-					Content: "You don't have any events to sync! You're ready to start creating events!",
-				}
-			} else {
-				webhookParams = &discordgo.WebhookParams{
-					Content: "Select the channels you want to assign to these already existing events.\n" +
-						"If no channel is selected for an event, one will be created.\n" +
-						"Channels selected here will be made private, renamed, and the users marked as interested will be given access.",
-					Components: append(selects, &discordgo.ActionsRow{
-						Components: []discordgo.MessageComponent{
-							&discordgo.Button{
-								CustomID: "finish",
-								Label:    "Done",
-								Style:    discordgo.SuccessButton,
-							},
-						},
-					}),
-				}
-			}
-			_, err = s.FollowupMessageCreate(i.Interaction, true, webhookParams)
-			if err != nil {
-				return err
-			}
+		if i.ApplicationCommandData().Name == cmdEventChannels.Name {
+			return em.handleCommand(ctx, log, s, i)
 		}
 	case discordgo.InteractionMessageComponent:
-		data := i.Data.(discordgo.MessageComponentInteractionData)
-		switch data.CustomID {
-		case "finish":
-			err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseDeferredMessageUpdate,
-			})
-			if err != nil {
-				return err
-			}
-
-			message := "finishing up..."
-			components := make([]discordgo.MessageComponent, 0)
-			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content:    &message,
-				Components: &components,
-			})
-			if err != nil {
-				return err
-			}
-
-			events, err := s.GuildScheduledEvents(i.GuildID, false)
-			if err != nil {
-				return err
-			}
-
-			var guild Guild
-			found, err := em.engine.Context(ctx).ID(i.GuildID).Get(&guild)
-			if err != nil {
-				return err
-			}
-
-			if !found {
-				return fmt.Errorf("could not find guild")
-			}
-
-			var internalEvents = map[string]*Event{}
-			err = em.engine.Context(ctx).Where("guild_id = ?", i.GuildID).Find(&internalEvents)
-			if err != nil {
-				return err
-			}
-
-			var roles []*discordgo.Role
-			roles, err = s.GuildRoles(i.GuildID)
-			if err != nil {
-				return fmt.Errorf("failed to create role: %w", err)
-			}
-			var atEveryoneRole *discordgo.Role
-			for _, role := range roles {
-				if role.Name == "@everyone" {
-					atEveryoneRole = role
-					break
-				}
-			}
-			if atEveryoneRole == nil {
-				return fmt.Errorf("failed to find @everyone role")
-			}
-
-			for _, event := range events {
-				internalEvent, found := internalEvents[event.ID]
-				if !found {
-					err := em.onGuildEventCreate(ctx, log, s, &discordgo.GuildScheduledEventCreate{
-						GuildScheduledEvent: event,
-					})
-					if err != nil {
-						return err
-					}
-				} else {
-					permissionOverwrites := []*discordgo.PermissionOverwrite{
-						{
-							ID:    s.State.User.ID,
-							Type:  discordgo.PermissionOverwriteTypeMember,
-							Allow: discordgo.PermissionViewChannel,
-						},
-						{
-							ID:   atEveryoneRole.ID,
-							Type: discordgo.PermissionOverwriteTypeRole,
-							Deny: discordgo.PermissionViewChannel,
-						},
-					}
-
-					var lastID string
-					for {
-						eventUsers, err := s.GuildScheduledEventUsers(i.GuildID, event.ID, 100, false, "", lastID)
-						if err != nil {
-							return err
-						}
-						if len(eventUsers) == 0 {
-							break
-						}
-						lastID = eventUsers[len(eventUsers)-1].User.ID
-						for _, eventUser := range eventUsers {
-							permissionOverwrites = append(permissionOverwrites, &discordgo.PermissionOverwrite{
-								ID:    eventUser.User.ID,
-								Type:  discordgo.PermissionOverwriteTypeMember,
-								Allow: discordgo.PermissionViewChannel,
-							})
-						}
-					}
-
-					_, err = s.ChannelEditComplex(internalEvent.ChannelID, &discordgo.ChannelEdit{
-						Name:                 eventChannelName(event.Name),
-						ParentID:             guild.EventChannelParentID,
-						PermissionOverwrites: permissionOverwrites,
-					})
-					if err != nil {
-						return err
-					}
-				}
-			}
-
-			guild.FirstReconcileRun = true
-			_, err = em.engine.Context(ctx).ID(guild.ID).UseBool().Update(&guild)
-			if err != nil {
-				return err
-			}
-
-			content := "Channels linked!"
-			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: &content,
-			})
-			if err != nil {
-				return err
-			}
-		default:
-			if len(data.Values) != 1 {
-				_, _ = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-					Content: "No value selected",
-				})
-				return nil
-			}
-			channelID := data.Values[0]
-
-			err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseDeferredMessageUpdate,
-			})
-			if err != nil {
-				return err
-			}
-
-			_, err = em.engine.Context(ctx).Insert(&Event{
-				ID:                data.CustomID,
-				GuildID:           i.GuildID,
-				ChannelID:         channelID,
-				AnnounceMessageID: nil,
-			})
-			if isErrDuplicatePGConstraint(err) {
-				_, err = em.engine.Context(ctx).Update(&Event{
-					ID:                data.CustomID,
-					GuildID:           i.GuildID,
-					ChannelID:         channelID,
-					AnnounceMessageID: nil,
-				})
-			}
-			if err != nil {
-				return err
-			}
-		}
+		return em.routeComponent(ctx, log, s, i, i.MessageComponentData().CustomID)
 	case discordgo.InteractionApplicationCommandAutocomplete:
+		return em.handleAutocomplete(ctx, log, s, i)
 	case discordgo.InteractionModalSubmit:
+		return em.routeComponent(ctx, log, s, i, i.ModalSubmitData().CustomID)
 	}
 
 	return nil
 }
 
-func (em *EventManager) getEventSelects(s *discordgo.Session, guildID string, disabled bool) ([]discordgo.MessageComponent, error) {
-	events, err := s.GuildScheduledEvents(guildID, false)
-	if err != nil {
-		return nil, err
-	}
-
-	channels, err := s.GuildChannels(guildID)
-	if err != nil {
-		return nil, err
-	}
-
-	selectOptions := make([]discordgo.SelectMenuOption, 0, len(channels))
-	for idx := range channels {
-		if channels[idx].Type != discordgo.ChannelTypeGuildText {
-			continue
-		}
-
-		selectOptions = append(selectOptions, discordgo.SelectMenuOption{
-			Label: channels[idx].Name,
-			Value: channels[idx].ID,
-		})
-	}
-
-	selects := make([]discordgo.MessageComponent, 0, 2*len(events))
-	for idx := range events {
-		selects = append(selects, &discordgo.ActionsRow{
-			Components: []discordgo.MessageComponent{
-				&discordgo.SelectMenu{
-					CustomID:    events[idx].ID,
-					Placeholder: events[idx].Name,
-					MaxValues:   1,
-					Options:     selectOptions,
-					Disabled:    disabled,
-				},
-			},
-		})
-	}
-	return selects, nil
-}
-
 func (em *EventManager) possiblyCreateGuild(ctx context.Context, m *discordgo.Guild) (guild *Guild, exists bool, err error) {
 	guild = &Guild{
 		ID:                         m.ID,
@@ -937,7 +846,21 @@ func (em *EventManager) possiblyCreateGuild(ctx context.Context, m *discordgo.Gu
 }
 
 func (em *EventManager) deleteEvent(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild, event *Event) (err error) {
-	if guild.DeleteWhenDone {
+	if event.InviteCode != "" {
+		err = deleteEventInvite(s, event.InviteCode)
+		if err != nil {
+			log.WithError(err).Warn("failed to delete invite")
+		}
+	}
+
+	if len(event.VoiceAccessIDs) > 0 {
+		err = em.revokeAllVoiceAccess(ctx, s, event)
+		if err != nil {
+			log.WithError(err).Warn("failed to revoke voice access")
+		}
+	}
+
+	if guild.DeleteWhenDone && event.ChannelID != "" {
 		_, err = s.ChannelDelete(event.ChannelID)
 		if err != nil {
 			log.WithError(err).Warn("failed to delete channel")
@@ -949,6 +872,11 @@ func (em *EventManager) deleteEvent(ctx context.Context, log *logrus.Entry, s *d
 		log.WithError(err).Warn("failed to delete event")
 	}
 
+	err = em.orderEventChannels(ctx, log, s, guild)
+	if err != nil {
+		log.WithError(err).Warn("failed to order event channels")
+	}
+
 	return nil
 }
 
@@ -971,3 +899,42 @@ func (em *EventManager) getGuildAndEvent(ctx context.Context, guildID string, ev
 
 	return guild, event, nil
 }
+
+// Applies modify to the stored Event and saves the columns when it reports a change, holding the lock of the Event
+// so concurrent updates of its lists don't overwrite each other. The Event passed in is refreshed with what is
+// stored.
+func (em *EventManager) modifyEvent(ctx context.Context, event *Event, modify func(event *Event) bool, cols ...string) error {
+	unlock := em.eventLocks.lock(event.ID)
+	defer unlock()
+
+	current := &Event{ID: event.ID}
+	found, err := em.engine.Context(ctx).Get(current)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("was not able to find internal event")
+	}
+
+	if modify(current) {
+		_, err = em.engine.Context(ctx).ID(current.ID).Cols(cols...).Update(current)
+		if err != nil {
+			return err
+		}
+	}
+
+	*event = *current
+	return nil
+}
+
+// Returns the list without the value.
+func removeString(values []string, value string) []string {
+	remaining := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			remaining = append(remaining, v)
+		}
+	}
+
+	return remaining
+}
@@ -0,0 +1,256 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// Runs the steps the EventSetup has not recorded yet: the channel, its invite, the announcement, the info message
+// and finally the Event itself. Each outcome is kept on the EventSetup before it is persisted, so a rollback undoes
+// it even when recording it failed.
+func (em *EventManager) runEventSetup(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild, setup *EventSetup, scheduledEvent *discordgo.GuildScheduledEvent) (*Event, error) {
+	record := func(cols ...string) error {
+		_, err := em.engine.Context(ctx).ID(setup.ID).Cols(cols...).Update(setup)
+		if err != nil {
+			return fmt.Errorf("failed to record setup: %w", err)
+		}
+		return nil
+	}
+
+	if setup.ChannelID == "" {
+		channel, err := em.createEventChannel(ctx, log, s, guild, scheduledEvent, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		setup.ChannelID = channel.ID
+		setup.SentChannelName = eventChannelName(scheduledEvent.Name)
+		setup.ChannelName = channel.Name
+		if err := record("channel_id", "sent_channel_name", "channel_name"); err != nil {
+			return nil, err
+		}
+	}
+
+	if setup.InviteCode == "" {
+		invite, err := createEventInvite(s, setup.ChannelID, scheduledEvent)
+		if err != nil {
+			return nil, err
+		}
+
+		setup.InviteCode = invite.Code
+		setup.InviteExpiresAt = invite.ExpiresAt
+		if err := record("invite_code", "invite_expires_at"); err != nil {
+			return nil, err
+		}
+	}
+
+	if guild.EventAnnouncementChannelID != "" && setup.AnnounceMessageID == "" {
+		message, err := s.ChannelMessageSendComplex(guild.EventAnnouncementChannelID, &discordgo.MessageSend{
+			Content:    guild.GetNewEventChannelMessage(scheduledEvent.Name, setup.InviteCode, scheduledEvent.ID),
+			Components: em.getMembershipComponents(scheduledEvent.ID),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to announce channel: %w", err)
+		}
+
+		setup.AnnounceChannelID = guild.EventAnnouncementChannelID
+		setup.AnnounceMessageID = message.ID
+		if err := record("announce_channel_id", "announce_message_id"); err != nil {
+			return nil, err
+		}
+	}
+
+	event := &Event{
+		ID:              scheduledEvent.ID,
+		GuildID:         scheduledEvent.GuildID,
+		ChannelID:       setup.ChannelID,
+		InviteCode:      setup.InviteCode,
+		InviteExpiresAt: setup.InviteExpiresAt,
+		SentChannelName: setup.SentChannelName,
+		ChannelName:     setup.ChannelName,
+	}
+	if setup.AnnounceMessageID != "" {
+		announceMessageID := setup.AnnounceMessageID
+		event.AnnounceMessageID = &announceMessageID
+	}
+
+	if setup.InfoMessageID == "" {
+		message, err := em.sendEventInfoMessage(log, s, event, getEventInviteURL(setup.InviteCode, event.ID), getEventInfoEmbed(event, scheduledEvent))
+		if err != nil {
+			return nil, err
+		}
+
+		setup.InfoMessageID = message.ID
+		if err := record("info_message_id"); err != nil {
+			return nil, err
+		}
+	}
+	event.InfoMessageID = setup.InfoMessageID
+
+	_, err := em.engine.Context(ctx).Insert(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert event: %w", err)
+	}
+
+	// Finding the Event at startup is enough to know this setup finished.
+	_, err = em.engine.Context(ctx).ID(setup.ID).Delete(&EventSetup{})
+	if err != nil {
+		log.WithError(err).Warn("failed to remove finished setup")
+	}
+
+	return event, nil
+}
+
+// Undoes the recorded steps of the EventSetup in reverse. Steps that could not be undone stay recorded, so the
+// rollback is tried again at the next startup.
+func (em *EventManager) rollbackEventSetup(ctx context.Context, log *logrus.Entry, s *discordgo.Session, setup *EventSetup) error {
+	var failed error
+	undone := func(err error, what string) bool {
+		if err == nil || isDiscordErrRESTCode(err, http.StatusNotFound) {
+			return true
+		}
+
+		log.WithError(err).Errorf("failed to clean up %s", what)
+		failed = err
+		return false
+	}
+
+	if setup.InfoMessageID != "" && undone(s.ChannelMessageDelete(setup.ChannelID, setup.InfoMessageID), "info message") {
+		setup.InfoMessageID = ""
+	}
+
+	if setup.AnnounceMessageID != "" && undone(s.ChannelMessageDelete(setup.AnnounceChannelID, setup.AnnounceMessageID), "announce message") {
+		setup.AnnounceChannelID = ""
+		setup.AnnounceMessageID = ""
+	}
+
+	if setup.InviteCode != "" && undone(deleteEventInvite(s, setup.InviteCode), "invite") {
+		setup.InviteCode = ""
+		setup.InviteExpiresAt = nil
+	}
+
+	if setup.ChannelID != "" {
+		_, err := s.ChannelDelete(setup.ChannelID)
+		if undone(err, "channel") {
+			setup.ChannelID = ""
+		}
+	}
+
+	if failed != nil {
+		_, err := em.engine.Context(ctx).ID(setup.ID).AllCols().Update(setup)
+		if err != nil {
+			log.WithError(err).Error("failed to record rollback")
+		}
+		return failed
+	}
+
+	_, err := em.engine.Context(ctx).ID(setup.ID).Delete(&EventSetup{})
+	return err
+}
+
+// Finishes the setups of the discordgo.Guild that were interrupted, or rolls them back when their event or
+// channel is gone or finishing them fails.
+func (em *EventManager) resumeEventSetups(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guildID string) error {
+	var setups []*EventSetup
+	err := em.engine.Context(ctx).Where("guild_id = ?", guildID).Find(&setups)
+	if err != nil {
+		return err
+	}
+
+	if len(setups) == 0 {
+		return nil
+	}
+
+	var guild Guild
+	_, err = em.engine.Context(ctx).ID(guildID).Get(&guild)
+	if err != nil {
+		return err
+	}
+
+	for _, setup := range setups {
+		err = em.resumeEventSetup(ctx, log.WithField("event_id", setup.ID), s, &guild, setup.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Finishes or rolls back a single interrupted setup. It holds the lock of the event, like onGuildEventCreate, and
+// reads the EventSetup again under it since onGuildEventCreate may have finished or replaced it meanwhile.
+func (em *EventManager) resumeEventSetup(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild, setupID string) error {
+	unlock := em.eventLocks.lock(setupID)
+	defer unlock()
+
+	setup := &EventSetup{ID: setupID}
+	found, err := em.engine.Context(ctx).Get(setup)
+	if err != nil || !found {
+		return err
+	}
+
+	exists, err := em.engine.Context(ctx).Exist(&Event{ID: setup.ID})
+	if err != nil {
+		return err
+	}
+	if exists {
+		_, err = em.engine.Context(ctx).ID(setup.ID).Delete(&EventSetup{})
+		return err
+	}
+
+	resumable, scheduledEvent, err := isEventSetupResumable(s, setup)
+	if err != nil {
+		return err
+	}
+
+	if resumable {
+		_, err = em.runEventSetup(ctx, log, s, guild, setup, scheduledEvent)
+		if err == nil {
+			log.Info("finished interrupted event channel setup")
+			return nil
+		}
+
+		log.WithError(err).Warn("failed to finish interrupted event channel setup")
+	}
+
+	err = em.rollbackEventSetup(ctx, log, s, setup)
+	if err != nil {
+		log.WithError(err).Error("failed to roll back interrupted event channel setup")
+		return nil
+	}
+
+	log.Info("rolled back interrupted event channel setup")
+	return nil
+}
+
+// Whether the event of the EventSetup still takes place and the channel created for it still exists.
+func isEventSetupResumable(s *discordgo.Session, setup *EventSetup) (bool, *discordgo.GuildScheduledEvent, error) {
+	scheduledEvent, err := s.GuildScheduledEvent(setup.GuildID, setup.ID, false)
+	if err != nil {
+		if isDiscordErrRESTCode(err, http.StatusNotFound) {
+			return false, nil, nil
+		}
+		return false, nil, fmt.Errorf("failed to get scheduled event: %w", err)
+	}
+
+	switch scheduledEvent.Status {
+	case discordgo.GuildScheduledEventStatusCompleted, discordgo.GuildScheduledEventStatusCanceled:
+		return false, nil, nil
+	}
+
+	if setup.ChannelID != "" {
+		_, err = s.Channel(setup.ChannelID)
+		if err != nil {
+			if isDiscordErrRESTCode(err, http.StatusNotFound) {
+				return false, nil, nil
+			}
+			return false, nil, fmt.Errorf("failed to get channel: %w", err)
+		}
+	}
+
+	return true, scheduledEvent, nil
+}
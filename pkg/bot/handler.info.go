@@ -0,0 +1,194 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+func getEventURL(guildID string, eventID string) string {
+	return fmt.Sprintf("https://discord.com/events/%s/%s", guildID, eventID)
+}
+
+// The embed pinned in the event channel, describing the event and how many members are interested in it.
+func getEventInfoEmbed(event *Event, scheduledEvent *discordgo.GuildScheduledEvent) *discordgo.MessageEmbed {
+	hosts := make([]string, 0, len(event.CohostIDs)+1)
+	for _, hostID := range getEventHostIDs(scheduledEvent, event.CohostIDs) {
+		hosts = append(hosts, fmt.Sprintf("<@%s>", hostID))
+	}
+
+	when := fmt.Sprintf("<t:%d:F> (<t:%d:R>)", scheduledEvent.ScheduledStartTime.Unix(), scheduledEvent.ScheduledStartTime.Unix())
+	if scheduledEvent.ScheduledEndTime != nil {
+		when += fmt.Sprintf("\nuntil <t:%d:F>", scheduledEvent.ScheduledEndTime.Unix())
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       scheduledEvent.Name,
+		URL:         getEventURL(scheduledEvent.GuildID, scheduledEvent.ID),
+		Description: scheduledEvent.Description,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Host", Value: truncateEmbedFieldValue(statusText(strings.Join(hosts, ", "))), Inline: true},
+			{Name: "Interested", Value: fmt.Sprintf("%d", scheduledEvent.UserCount), Inline: true},
+			{Name: "When", Value: when},
+		},
+	}
+
+	if location := getEventLocation(scheduledEvent); location != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Where", Value: location})
+	}
+
+	return embed
+}
+
+// Whether the message already shows the invite, the embed and the buttons, so unchanged info is not edited again.
+func isSameEventInfo(message *discordgo.Message, content string, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) bool {
+	if message.Content != content || len(message.Embeds) != 1 {
+		return false
+	}
+
+	// Buttons sent by an older version may not be understood anymore.
+	currentIDs, expectedIDs := getComponentCustomIDs(message.Components), getComponentCustomIDs(components)
+	if len(currentIDs) != len(expectedIDs) {
+		return false
+	}
+	for i := range expectedIDs {
+		if currentIDs[i] != expectedIDs[i] {
+			return false
+		}
+	}
+
+	current := message.Embeds[0]
+	if current.Title != embed.Title || current.URL != embed.URL || current.Description != embed.Description || len(current.Fields) != len(embed.Fields) {
+		return false
+	}
+
+	for i := range embed.Fields {
+		if current.Fields[i].Name != embed.Fields[i].Name || current.Fields[i].Value != embed.Fields[i].Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Updates the info message of the event once its attendees stopped changing for channelUpdateDelay.
+func (em *EventManager) scheduleEventInfoUpdate(log *logrus.Entry, s *discordgo.Session, guildID string, eventID string) {
+	em.channelUpdates.schedule("info:"+eventID, func() {
+		err := em.updateEventInfoMessage(context.Background(), log, s, guildID, eventID)
+		if err != nil {
+			log.WithError(err).Error("failed to update event info message")
+		}
+	})
+}
+
+// Edits the pinned info message of the event in place, posting and pinning a new one when there is none yet or
+// it was deleted.
+func (em *EventManager) updateEventInfoMessage(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guildID string, eventID string) error {
+	guild, event, err := em.getGuildAndEvent(ctx, guildID, eventID)
+	if err != nil {
+		return err
+	}
+
+	if event == nil || event.ChannelID == "" || event.Unlinked {
+		return nil
+	}
+
+	// Only the single event endpoint counts the interested users.
+	scheduledEvent, err := s.GuildScheduledEvent(guildID, eventID, true)
+	if err != nil {
+		if isDiscordErrRESTCode(err, http.StatusNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get scheduled event: %w", err)
+	}
+
+	embed := getEventInfoEmbed(event, scheduledEvent)
+
+	var content string
+	inviteCode, err := em.ensureEventInvite(ctx, log, s, guild, event, scheduledEvent)
+	if err != nil {
+		log.WithError(err).Warn("failed to get event invite")
+	} else {
+		content = getEventInviteURL(inviteCode, event.ID)
+	}
+
+	if event.InfoMessageID != "" {
+		message, err := s.ChannelMessage(event.ChannelID, event.InfoMessageID)
+		if err == nil {
+			components := em.getMembershipComponents(event.ID)
+			if isSameEventInfo(message, content, embed, components) {
+				return nil
+			}
+
+			_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+				ID:         event.InfoMessageID,
+				Channel:    event.ChannelID,
+				Content:    &content,
+				Embeds:     []*discordgo.MessageEmbed{embed},
+				Components: components,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to edit info message: %w", err)
+			}
+			return nil
+		}
+		if !isDiscordErrRESTCode(err, http.StatusNotFound) {
+			return fmt.Errorf("failed to get info message: %w", err)
+		}
+
+		log.Info("info message was deleted, posting it again")
+	}
+
+	message, err := em.sendEventInfoMessage(log, s, event, content, embed)
+	if err != nil {
+		return err
+	}
+
+	event.InfoMessageID = message.ID
+	_, err = em.engine.Context(ctx).ID(event.ID).Cols("info_message_id").Update(event)
+	return err
+}
+
+// Posts the info message with the Join and Leave buttons in the event channel and pins it.
+func (em *EventManager) sendEventInfoMessage(log *logrus.Entry, s *discordgo.Session, event *Event, content string, embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
+	message, err := s.ChannelMessageSendComplex(event.ChannelID, &discordgo.MessageSend{
+		Content:    content,
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: em.getMembershipComponents(event.ID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to post info message: %w", err)
+	}
+
+	err = s.ChannelMessagePin(event.ChannelID, message.ID)
+	if err != nil {
+		log.WithError(err).Warn("failed to pin info message")
+	}
+
+	return message, nil
+}
+
+// The CustomIDs of the buttons and selects, in the order they are shown.
+func getComponentCustomIDs(components []discordgo.MessageComponent) []string {
+	var customIDs []string
+	for _, component := range components {
+		switch component := component.(type) {
+		case discordgo.ActionsRow:
+			customIDs = append(customIDs, getComponentCustomIDs(component.Components)...)
+		case *discordgo.ActionsRow:
+			customIDs = append(customIDs, getComponentCustomIDs(component.Components)...)
+		case discordgo.Button:
+			customIDs = append(customIDs, component.CustomID)
+		case *discordgo.Button:
+			customIDs = append(customIDs, component.CustomID)
+		case *discordgo.SelectMenu:
+			customIDs = append(customIDs, component.CustomID)
+		}
+	}
+
+	return customIDs
+}
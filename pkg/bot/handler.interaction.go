@@ -0,0 +1,264 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// Discord invalidates an interaction that isn't acknowledged within 3 seconds, so we defer a bit before that.
+const interactionAutoDeferAfter = 2 * time.Second
+
+type interactionState int
+
+const (
+	interactionStateUnacknowledged interactionState = iota
+	interactionStateDeferred
+	interactionStateResponded
+)
+
+// interactionContext wraps a discordgo.InteractionCreate and remembers how it was acknowledged, so handlers can
+// just respond or update and the right one of InteractionRespond, InteractionResponseEdit or
+// FollowupMessageCreate is picked for them.
+type interactionContext struct {
+	*discordgo.InteractionCreate
+
+	s   *discordgo.Session
+	log *logrus.Entry
+
+	// Makes every message we send ephemeral.
+	ephemeral bool
+
+	mu        sync.Mutex
+	state     interactionState
+	autoDefer *time.Timer
+}
+
+func newInteractionContext(s *discordgo.Session, log *logrus.Entry, i *discordgo.InteractionCreate) *interactionContext {
+	return &interactionContext{
+		InteractionCreate: i,
+		s:                 s,
+		log:               log,
+		ephemeral:         true,
+	}
+}
+
+// Defers the interaction if the handler hasn't acknowledged it by the time Discord's deadline gets close.
+func (i *interactionContext) startAutoDefer() {
+	i.autoDefer = time.AfterFunc(interactionAutoDeferAfter, func() {
+		err := i.deferResponse()
+		if err != nil {
+			i.log.WithError(err).Warn("failed to auto defer interaction")
+			return
+		}
+	})
+}
+
+func (i *interactionContext) stopAutoDefer() {
+	if i.autoDefer != nil {
+		i.autoDefer.Stop()
+	}
+}
+
+// The ID of the discordgo.User who triggered the interaction, whether in a discordgo.Guild or a DM.
+func (i *interactionContext) userID() string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+
+	return ""
+}
+
+func (i *interactionContext) isComponent() bool {
+	return i.Type == discordgo.InteractionMessageComponent || i.Type == discordgo.InteractionModalSubmit
+}
+
+func (i *interactionContext) flags() discordgo.MessageFlags {
+	if i.ephemeral {
+		return discordgo.MessageFlagsEphemeral
+	}
+
+	return 0
+}
+
+// Acknowledges the interaction without a visible answer yet, doing nothing if it already was acknowledged.
+func (i *interactionContext) deferResponse() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.state != interactionStateUnacknowledged {
+		return nil
+	}
+
+	response := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: i.flags(),
+		},
+	}
+	if i.isComponent() {
+		response = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredMessageUpdate,
+		}
+	}
+
+	err := i.s.InteractionRespond(i.Interaction, response)
+	if err != nil {
+		return err
+	}
+
+	i.state = interactionStateDeferred
+	return nil
+}
+
+// Sends a message answering the interaction: the initial response, the edit of a deferred command response or a
+// followup once one of those was sent.
+func (i *interactionContext) respond(data *discordgo.InteractionResponseData) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	data.Flags |= i.flags()
+
+	switch {
+	case i.state == interactionStateUnacknowledged:
+		err := i.s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: data,
+		})
+		if err != nil {
+			return err
+		}
+	case i.state == interactionStateDeferred && !i.isComponent():
+		_, err := i.s.InteractionResponseEdit(i.Interaction, webhookEditFromResponseData(data))
+		if err != nil {
+			return err
+		}
+	default:
+		_, err := i.s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content:    data.Content,
+			Components: data.Components,
+			Embeds:     data.Embeds,
+			Flags:      data.Flags,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	i.state = interactionStateResponded
+	return nil
+}
+
+// Replaces the message a component is attached to, or the original response of a command.
+func (i *interactionContext) update(data *discordgo.InteractionResponseData) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var err error
+	switch {
+	case i.state == interactionStateUnacknowledged && i.isComponent():
+		err = i.s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: data,
+		})
+	case i.state == interactionStateUnacknowledged:
+		data.Flags |= i.flags()
+		err = i.s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: data,
+		})
+	default:
+		_, err = i.s.InteractionResponseEdit(i.Interaction, webhookEditFromResponseData(data))
+	}
+	if err != nil {
+		return err
+	}
+
+	i.state = interactionStateResponded
+	return nil
+}
+
+// Opens a modal, which Discord only allows as the initial response.
+func (i *interactionContext) showModal(data *discordgo.InteractionResponseData) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.state != interactionStateUnacknowledged {
+		return fmt.Errorf("cannot show a modal for an acknowledged interaction")
+	}
+
+	err := i.s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: data,
+	})
+	if err != nil {
+		return err
+	}
+
+	i.state = interactionStateResponded
+	return nil
+}
+
+func (i *interactionContext) autocomplete(choices []*discordgo.ApplicationCommandOptionChoice) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	err := i.s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	i.state = interactionStateResponded
+	return nil
+}
+
+// Logs the error under a short correlation ID and tells the user that ID so a report can be matched to the logs.
+func (i *interactionContext) reportError(err error) {
+	correlationID := newCorrelationID()
+	i.log.WithError(err).WithField("correlation_id", correlationID).Error("failed")
+
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		return
+	}
+
+	err = i.respond(&discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Something went wrong (reference `%s`).", correlationID),
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+	if err != nil {
+		i.log.WithError(err).WithField("correlation_id", correlationID).Warn("failed to report error")
+	}
+}
+
+func webhookEditFromResponseData(data *discordgo.InteractionResponseData) *discordgo.WebhookEdit {
+	edit := &discordgo.WebhookEdit{
+		Content: &data.Content,
+	}
+	if data.Components != nil {
+		edit.Components = &data.Components
+	}
+	if data.Embeds != nil {
+		edit.Embeds = &data.Embeds
+	}
+
+	return edit
+}
+
+func newCorrelationID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
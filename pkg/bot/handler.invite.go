@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// Discord refuses invites living longer than this, unless they never expire.
+const maxInviteAge = 7 * 24 * time.Hour
+
+// How long the invite of an event without an end time stays valid after it started.
+const defaultEventDuration = 24 * time.Hour
+
+// How long before it expires an invite is renewed, when its event has not ended by then. Longer than
+// backgroundTaskInterval, so the invite is renewed before it lapses.
+const inviteRenewMargin = 24 * time.Hour
+
+// When the event ends, or is assumed to when it has no end time.
+func getEventEnd(scheduledEvent *discordgo.GuildScheduledEvent) time.Time {
+	if scheduledEvent.ScheduledEndTime != nil {
+		return *scheduledEvent.ScheduledEndTime
+	}
+
+	return scheduledEvent.ScheduledStartTime.Add(defaultEventDuration)
+}
+
+// How long the invite to an event channel is valid: until the event ends, as far as Discord allows.
+func getEventInviteMaxAge(scheduledEvent *discordgo.GuildScheduledEvent, now time.Time) time.Duration {
+	age := getEventEnd(scheduledEvent).Sub(now)
+	if age > maxInviteAge {
+		age = maxInviteAge
+	}
+	if age < time.Minute {
+		age = time.Minute
+	}
+
+	return age.Truncate(time.Second)
+}
+
+// Whether the invite must be renewed: it lapses soon and before the event ends. A nil expiry never lapses.
+func isEventInviteExpiring(expiresAt *time.Time, scheduledEvent *discordgo.GuildScheduledEvent, now time.Time) bool {
+	if expiresAt == nil {
+		return false
+	}
+
+	return expiresAt.Before(getEventEnd(scheduledEvent)) && expiresAt.Before(now.Add(inviteRenewMargin))
+}
+
+// Creates an invite to the event channel expiring when the event ends, ExpiresAt is always set on the result.
+func createEventInvite(s *discordgo.Session, channelID string, scheduledEvent *discordgo.GuildScheduledEvent) (*discordgo.Invite, error) {
+	now := time.Now()
+	maxAge := getEventInviteMaxAge(scheduledEvent, now)
+
+	invite, err := s.ChannelInviteCreate(channelID, discordgo.Invite{
+		MaxAge:    int(maxAge.Seconds()),
+		MaxUses:   0,
+		Temporary: false,
+		Unique:    true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invite: %w", err)
+	}
+
+	if invite.ExpiresAt == nil {
+		expiresAt := now.Add(maxAge)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	return invite, nil
+}
+
+// Returns the invite code stored on the Event while it still leads to its channel and does not lapse before the
+// event ends, otherwise creates a new invite, stores it and points the announcement at it, so re-syncing an event
+// does not leave another invite behind each time.
+func (em *EventManager) ensureEventInvite(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild, event *Event, scheduledEvent *discordgo.GuildScheduledEvent) (string, error) {
+	if event.InviteCode != "" {
+		invite, err := s.Invite(event.InviteCode)
+		if err == nil && invite.Channel != nil && invite.Channel.ID == event.ChannelID &&
+			!isEventInviteExpiring(event.InviteExpiresAt, scheduledEvent, time.Now()) {
+			return event.InviteCode, nil
+		}
+		if err != nil && !isDiscordErrRESTCode(err, http.StatusNotFound) {
+			return "", fmt.Errorf("failed to get invite: %w", err)
+		}
+
+		// The invite leads to a channel the event no longer uses, or lapses before the event ends.
+		if err == nil {
+			_ = deleteEventInvite(s, event.InviteCode)
+		}
+	}
+
+	invite, err := createEventInvite(s, event.ChannelID, scheduledEvent)
+	if err != nil {
+		return "", err
+	}
+
+	event.InviteCode = invite.Code
+	event.InviteExpiresAt = invite.ExpiresAt
+	_, err = em.engine.Context(ctx).ID(event.ID).Cols("invite_code", "invite_expires_at").Update(event)
+	if err != nil {
+		return "", err
+	}
+
+	if event.AnnounceMessageID != nil && guild.EventAnnouncementChannelID != "" {
+		_, err = s.ChannelMessageEdit(guild.EventAnnouncementChannelID, *event.AnnounceMessageID, guild.GetNewEventChannelMessage(scheduledEvent.Name, invite.Code, event.ID))
+		if err != nil && !isDiscordErrRESTCode(err, http.StatusNotFound) {
+			log.WithError(err).Warn("failed to update announcement invite")
+		}
+	}
+
+	return invite.Code, nil
+}
+
+// Renews the invites lapsing before their event ends, by updating the info message that shows them.
+func (em *EventManager) renewEventInvites(ctx context.Context, log *logrus.Entry, s *discordgo.Session) error {
+	var events []*Event
+	err := em.engine.Context(ctx).
+		Where("unlinked = ? AND channel_id <> '' AND invite_expires_at IS NOT NULL AND invite_expires_at < ?", false, time.Now().Add(inviteRenewMargin)).
+		Find(&events)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		err = em.updateEventInfoMessage(ctx, log, s, event.GuildID, event.ID)
+		if err != nil {
+			log.WithError(err).WithField("event_id", event.ID).Warn("failed to renew event invite")
+		}
+	}
+
+	return nil
+}
+
+// Revokes the invite, which may already be gone.
+func deleteEventInvite(s *discordgo.Session, code string) error {
+	_, err := s.InviteDelete(code)
+	if err != nil && !isDiscordErrRESTCode(err, http.StatusNotFound) {
+		return fmt.Errorf("failed to delete invite: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCreateEventInvite(t *testing.T) {
+	const channelID = "500000000000000001"
+
+	now := time.Now()
+	endsSoon := now.Add(2 * time.Hour)
+
+	tests := []struct {
+		name           string
+		scheduledEvent *discordgo.GuildScheduledEvent
+		maxAge         time.Duration
+	}{
+		{"ends before the cap", &discordgo.GuildScheduledEvent{ScheduledStartTime: now.Add(time.Hour), ScheduledEndTime: &endsSoon}, 2 * time.Hour},
+		{"without an end time", &discordgo.GuildScheduledEvent{ScheduledStartTime: now.Add(time.Hour)}, time.Hour + defaultEventDuration},
+		// Discord caps expiring invites at a week, ensureEventInvite renews them until the event ends.
+		{"beyond the cap", &discordgo.GuildScheduledEvent{ScheduledStartTime: now.Add(30 * 24 * time.Hour)}, maxInviteAge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var method, path string
+			var body map[string]interface{}
+			s := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+				method, path = r.Method, r.URL.Path
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				_ = json.NewEncoder(w).Encode(discordgo.Invite{Code: "abc"})
+			}, 0)
+
+			invite, err := createEventInvite(s, channelID, test.scheduledEvent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if invite.Code != "abc" {
+				t.Errorf("got code %q, want %q", invite.Code, "abc")
+			}
+			if method != http.MethodPost || path != "/api/v9/channels/"+channelID+"/invites" {
+				t.Errorf("sent %s %s", method, path)
+			}
+
+			// The test takes a few seconds at most between computing the expected and the sent age.
+			maxAge, _ := body["max_age"].(float64)
+			if diff := test.maxAge.Seconds() - maxAge; diff < 0 || diff > 5 {
+				t.Errorf("sent max_age = %v, want %v", body["max_age"], test.maxAge.Seconds())
+			}
+			if invite.ExpiresAt == nil || invite.ExpiresAt.Before(now.Add(test.maxAge-5*time.Second)) || invite.ExpiresAt.After(now.Add(test.maxAge+5*time.Second)) {
+				t.Errorf("got expiry %v, want %v", invite.ExpiresAt, now.Add(test.maxAge))
+			}
+
+			expected := map[string]interface{}{
+				"max_uses":  float64(0),
+				"temporary": false,
+				"unique":    true,
+			}
+			for key, value := range expected {
+				if body[key] != value {
+					t.Errorf("sent %s = %v, want %v", key, body[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestIsEventInviteExpiring(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		start     time.Time
+		end       *time.Time
+		expiring  bool
+	}{
+		{"never expires", nil, now.Add(30 * 24 * time.Hour), nil, false},
+		{"lapses soon before the event ends", at(time.Hour), now.Add(30 * 24 * time.Hour), nil, true},
+		{"lapses later before the event ends", at(3 * 24 * time.Hour), now.Add(30 * 24 * time.Hour), nil, false},
+		{"lapses soon with the event", at(time.Hour), now, at(time.Hour), false},
+		{"lapses soon before the default end", at(time.Hour), now, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scheduledEvent := &discordgo.GuildScheduledEvent{ScheduledStartTime: test.start, ScheduledEndTime: test.end}
+			if expiring := isEventInviteExpiring(test.expiresAt, scheduledEvent, now); expiring != test.expiring {
+				t.Errorf("got %v, want %v", expiring, test.expiring)
+			}
+		})
+	}
+}
+
+func TestDeleteEventInvite(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		fails  bool
+	}{
+		{"deleted", http.StatusOK, false},
+		{"already gone", http.StatusNotFound, false},
+		{"not allowed", http.StatusForbidden, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var method, path string
+			s := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+				method, path = r.Method, r.URL.Path
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(`{"code": "abc"}`))
+			}, 0)
+			s.MaxRestRetries = 0
+
+			err := deleteEventInvite(s, "abc")
+			if (err != nil) != test.fails {
+				t.Errorf("got error %v, want failure %v", err, test.fails)
+			}
+			if method != http.MethodDelete || path != "/api/v9/invites/abc" {
+				t.Errorf("sent %s %s", method, path)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+type MembershipAction = string
+
+const (
+	MembershipActionJoin  MembershipAction = "join"
+	MembershipActionLeave MembershipAction = "leave"
+)
+
+// The Join and Leave buttons are public, anyone who can see them may use them for themselves.
+const membershipComponentNamespace = "member"
+
+func (em *EventManager) membershipCustomID(eventID string, action MembershipAction) string {
+	return em.encodeComponentID(componentID{
+		Namespace: membershipComponentNamespace,
+		SessionID: eventID,
+		Action:    action,
+	})
+}
+
+// The Join and Leave buttons attached to the announcement and the pinned info message of an event.
+func (em *EventManager) getMembershipComponents(eventID string) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Join",
+					Style:    discordgo.SuccessButton,
+					CustomID: em.membershipCustomID(eventID, MembershipActionJoin),
+				},
+				discordgo.Button{
+					Label:    "Leave",
+					Style:    discordgo.SecondaryButton,
+					CustomID: em.membershipCustomID(eventID, MembershipActionLeave),
+				},
+			},
+		},
+	}
+}
+
+// Grants or revokes access to the event channel for whoever pressed the button, remembering them as an explicit
+// member so reconcile keeps them in regardless of Discord's interested list.
+func (em *EventManager) handleMembershipComponent(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, id componentID) error {
+	userID := i.userID()
+
+	guild, event, err := em.getGuildAndEvent(ctx, i.GuildID, id.SessionID)
+	if err != nil {
+		return err
+	}
+
+	if event == nil || event.ChannelID == "" || event.Unlinked {
+		return i.respond(&discordgo.InteractionResponseData{
+			Content: "This event no longer has a channel.",
+		})
+	}
+
+	scheduledEvent, err := s.GuildScheduledEvent(i.GuildID, event.ID, false)
+	if err != nil {
+		return fmt.Errorf("failed to get scheduled event: %w", err)
+	}
+
+	log = log.WithField("user_id", userID)
+
+	switch id.Action {
+	case MembershipActionJoin:
+		err = em.modifyEvent(ctx, event, func(event *Event) bool {
+			if containsString(event.MemberIDs, userID) {
+				return false
+			}
+
+			event.MemberIDs = append(event.MemberIDs, userID)
+			return true
+		}, "member_ids")
+		if err != nil {
+			return err
+		}
+
+		allow, deny := getParticipantPermissions(guild)
+		if isEventHost(scheduledEvent, event.CohostIDs, userID) {
+			allow, deny = getHostPermissions(guild), 0
+		}
+
+		err = em.setEventMemberPermissions(s, guild, event.ChannelID, userID, allow, deny)
+		if err != nil {
+			return fmt.Errorf("failed to add permissions to channel: %w", err)
+		}
+
+		log.Info("member joined event channel")
+		return i.respond(&discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("You joined <#%s>.", event.ChannelID),
+		})
+	case MembershipActionLeave:
+		err = em.modifyEvent(ctx, event, func(event *Event) bool {
+			if !containsString(event.MemberIDs, userID) {
+				return false
+			}
+
+			event.MemberIDs = removeString(event.MemberIDs, userID)
+			return true
+		}, "member_ids")
+		if err != nil {
+			return err
+		}
+
+		if isEventHost(scheduledEvent, event.CohostIDs, userID) {
+			return i.respond(&discordgo.InteractionResponseData{
+				Content: "Hosts keep access to their event channel.",
+			})
+		}
+
+		interested, err := isScheduledEventUser(s, i.GuildID, event.ID, userID)
+		if err != nil {
+			return err
+		}
+		if interested {
+			return i.respond(&discordgo.InteractionResponseData{
+				Content: "You are still marked as interested in the event, remove your interest to leave the channel.",
+			})
+		}
+
+		em.markOwnChannelEdit(event.ChannelID)
+		err = s.ChannelPermissionDelete(event.ChannelID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to remove permissions for channel: %w", err)
+		}
+
+		log.Info("member left event channel")
+		return i.respond(&discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("You left <#%s>.", event.ChannelID),
+		})
+	}
+
+	return fmt.Errorf("unknown membership action %q", id.Action)
+}
@@ -0,0 +1,202 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+type OnboardingAction = string
+
+const (
+	OnboardingActionSetup OnboardingAction = "setup"
+	OnboardingActionMute  OnboardingAction = "mute"
+)
+
+const onboardingComponentNamespace = "onboard"
+
+// How long to wait between reminders while the configuration was not run, and how many to send at most.
+const (
+	onboardingNudgeInterval = 72 * time.Hour
+	maxOnboardingNudges     = 3
+)
+
+// Welcomes the owner of a new discordgo.Guild, or reminds them the bot still needs to be set up, and records where
+// the message went.
+func (em *EventManager) sendOnboarding(ctx context.Context, s *discordgo.Session, guild *Guild, discordGuild *discordgo.Guild, reminder bool) error {
+	message, err := em.sendToOwner(s, discordGuild, func(direct bool) *discordgo.MessageSend {
+		return em.getOnboardingMessage(discordGuild, direct, reminder)
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	guild.OnboardingChannelID = message.ChannelID
+	guild.LastNudgedAt = &now
+	if reminder {
+		guild.Nudges++
+	}
+	_, err = em.engine.Context(ctx).ID(guild.ID).Cols("onboarding_channel_id", "last_nudged_at", "nudges").Update(guild)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Reminds the owners of every discordgo.Guild that still has not run the configuration, a few days apart.
+func (em *EventManager) nudgeUnconfiguredGuilds(ctx context.Context, log *logrus.Entry, s *discordgo.Session) error {
+	var guilds []*Guild
+	err := em.engine.Context(ctx).
+		Where("configuration_was_run = ? AND nudges_muted = ? AND nudges < ?", false, false, maxOnboardingNudges).
+		And("last_nudged_at IS NULL OR last_nudged_at < ?", time.Now().Add(-onboardingNudgeInterval)).
+		Find(&guilds)
+	if err != nil {
+		return err
+	}
+
+	for _, guild := range guilds {
+		log := log.WithField("guild_id", guild.ID)
+
+		// Only guilds we are still in are in the state.
+		discordGuild, err := s.State.Guild(guild.ID)
+		if err != nil {
+			continue
+		}
+
+		err = em.sendOnboarding(ctx, s, guild, discordGuild, guild.LastNudgedAt != nil)
+		if err != nil {
+			log.WithError(err).Warn("failed to send onboarding reminder")
+			continue
+		}
+
+		log.WithField("nudges", guild.Nudges).Info("sent onboarding reminder")
+	}
+
+	return nil
+}
+
+// Delivers a message to the owner of the discordgo.Guild, falling back to its system channel and then its public
+// updates channel when their direct messages are closed. build is told whether the message reaches the owner alone.
+func (em *EventManager) sendToOwner(s *discordgo.Session, discordGuild *discordgo.Guild, build func(direct bool) *discordgo.MessageSend) (*discordgo.Message, error) {
+	channel, err := s.UserChannelCreate(discordGuild.OwnerID)
+	if err == nil {
+		var message *discordgo.Message
+		message, err = s.ChannelMessageSendComplex(channel.ID, build(true))
+		if err == nil {
+			return message, nil
+		}
+	}
+
+	for _, channelID := range []string{discordGuild.SystemChannelID, discordGuild.PublicUpdatesChannelID} {
+		if channelID == "" {
+			continue
+		}
+
+		var message *discordgo.Message
+		message, err = s.ChannelMessageSendComplex(channelID, build(false))
+		if err == nil {
+			return message, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to reach the owner: %w", err)
+}
+
+// Explains how to get started. Messages posted in a discordgo.Guild mention the owner, and their buttons can be used
+// by any event manager rather than only the owner.
+func (em *EventManager) getOnboardingMessage(discordGuild *discordgo.Guild, direct bool, reminder bool) *discordgo.MessageSend {
+	title := fmt.Sprintf("Thanks for adding Event Channels to %s!", discordGuild.Name)
+	if reminder {
+		title = fmt.Sprintf("Event Channels is not set up in %s yet", discordGuild.Name)
+	}
+
+	var content, userID string
+	var allowedMentions *discordgo.MessageAllowedMentions
+	if direct {
+		userID = discordGuild.OwnerID
+	} else {
+		content = fmt.Sprintf("<@%s> I couldn't send you a direct message, so here is how to get started.", discordGuild.OwnerID)
+		allowedMentions = &discordgo.MessageAllowedMentions{Users: []string{discordGuild.OwnerID}}
+	}
+
+	return &discordgo.MessageSend{
+		Content: content,
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       title,
+				Description: "Event Channels creates a channel for every scheduled event and lets in everyone marked as interested.",
+				Fields: []*discordgo.MessageEmbedField{
+					{
+						Name:  "1. Run the setup",
+						Value: "Press **Start setup** below or run `/event-channels config setup` in the server.",
+					},
+					{
+						Name:  "2. Link your existing channels",
+						Value: "The setup offers to link the channels you already use for your events, or to create new ones.",
+					},
+					{
+						Name:  "3. Let your organizers help",
+						Value: "`/event-channels config manager-role` lets a role manage event channels without the Manage Server permission, once the role may use the command in the integration settings. Allowing everyone there lets hosts add co-hosts with `/event-channels cohost`.",
+					},
+				},
+			},
+		},
+		Components: []discordgo.MessageComponent{
+			&discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					&discordgo.Button{
+						CustomID: em.onboardingCustomID(discordGuild.ID, userID, OnboardingActionSetup),
+						Label:    "Start setup",
+						Style:    discordgo.PrimaryButton,
+					},
+					&discordgo.Button{
+						CustomID: em.onboardingCustomID(discordGuild.ID, userID, OnboardingActionMute),
+						Label:    "Stop reminders",
+						Style:    discordgo.SecondaryButton,
+					},
+				},
+			},
+		},
+		AllowedMentions: allowedMentions,
+	}
+}
+
+// Like the setup wizard, the SessionID carries the discordgo.Guild as direct messages don't have one.
+func (em *EventManager) onboardingCustomID(guildID string, userID string, action OnboardingAction) string {
+	return em.encodeComponentID(componentID{
+		Namespace: onboardingComponentNamespace,
+		SessionID: guildID,
+		UserID:    userID,
+		Action:    action,
+	})
+}
+
+func (em *EventManager) handleOnboardingComponent(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, id componentID) error {
+	switch id.Action {
+	case OnboardingActionSetup:
+		response, err := em.startSetup(ctx, s, id.SessionID, i.userID())
+		if err != nil {
+			return err
+		}
+
+		return i.respond(response)
+	case OnboardingActionMute:
+		_, err := em.engine.Context(ctx).ID(id.SessionID).Cols("nudges_muted").Update(&Guild{NudgesMuted: true})
+		if err != nil {
+			return err
+		}
+
+		log.Info("onboarding reminders muted")
+
+		return i.respond(&discordgo.InteractionResponseData{
+			Content: "Got it, no more reminders. Run `/event-channels config setup` whenever you are ready.",
+		})
+	default:
+		return fmt.Errorf("unknown onboarding action %q", id.Action)
+	}
+}
@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// Moves the event channels in the event categories so the soonest event comes first, leaving every other channel
+// where it was. All positions that changed are sent to Discord in a single request.
+func (em *EventManager) orderEventChannels(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild) error {
+	if guild.EventChannelParentID == "" {
+		return nil
+	}
+
+	var events []*Event
+	err := em.engine.Context(ctx).Where("guild_id = ? AND unlinked = ? AND channel_id <> ''", guild.ID, false).Find(&events)
+	if err != nil {
+		return err
+	}
+
+	scheduledEvents, err := em.getCachedScheduledEvents(s, guild.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get scheduled events: %w", err)
+	}
+
+	scheduledEventMap := map[string]*discordgo.GuildScheduledEvent{}
+	for _, scheduledEvent := range scheduledEvents {
+		scheduledEventMap[scheduledEvent.ID] = scheduledEvent
+	}
+
+	startTimes := map[string]time.Time{}
+	for _, event := range events {
+		if scheduledEvent, has := scheduledEventMap[event.ID]; has {
+			startTimes[event.ChannelID] = scheduledEvent.ScheduledStartTime
+		}
+	}
+
+	channels, err := s.GuildChannels(guild.ID)
+	if err != nil {
+		return err
+	}
+
+	categories := map[string][]*discordgo.Channel{guild.EventChannelParentID: nil}
+	for _, overflowID := range guild.OverflowCategoryIDs {
+		categories[overflowID] = nil
+	}
+
+	for _, channel := range channels {
+		if _, has := categories[channel.ParentID]; has && channel.Type == discordgo.ChannelTypeGuildText {
+			categories[channel.ParentID] = append(categories[channel.ParentID], channel)
+		}
+	}
+
+	var reordered []*discordgo.Channel
+	for _, children := range categories {
+		reordered = append(reordered, getEventChannelOrder(children, startTimes)...)
+	}
+
+	if len(reordered) == 0 {
+		return nil
+	}
+
+	err = s.GuildChannelsReorder(guild.ID, reordered)
+	if err != nil {
+		return fmt.Errorf("failed to reorder channels: %w", err)
+	}
+
+	log.WithField("channels", len(reordered)).Debug("reordered event channels")
+	return nil
+}
+
+// Returns the channels of a category whose position has to change so the event channels, those with a start
+// time, are sorted by it while the other channels keep their slots.
+func getEventChannelOrder(channels []*discordgo.Channel, startTimes map[string]time.Time) []*discordgo.Channel {
+	sort.SliceStable(channels, func(i, j int) bool {
+		if channels[i].Position != channels[j].Position {
+			return channels[i].Position < channels[j].Position
+		}
+		return channels[i].ID < channels[j].ID
+	})
+
+	var eventChannels []*discordgo.Channel
+	for _, channel := range channels {
+		if _, has := startTimes[channel.ID]; has {
+			eventChannels = append(eventChannels, channel)
+		}
+	}
+
+	sort.SliceStable(eventChannels, func(i, j int) bool {
+		return startTimes[eventChannels[i].ID].Before(startTimes[eventChannels[j].ID])
+	})
+
+	var changed []*discordgo.Channel
+	next := 0
+	for position, channel := range channels {
+		if _, has := startTimes[channel.ID]; has {
+			channel = eventChannels[next]
+			next++
+		}
+
+		if channel.Position != position {
+			changed = append(changed, &discordgo.Channel{ID: channel.ID, Position: position})
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestGetEventChannelOrder(t *testing.T) {
+	now := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+	channel := func(id string, position int) *discordgo.Channel {
+		return &discordgo.Channel{ID: id, Position: position}
+	}
+
+	tests := []struct {
+		name       string
+		channels   []*discordgo.Channel
+		startTimes map[string]time.Time
+		expected   []*discordgo.Channel
+	}{
+		{
+			name:       "empty category",
+			channels:   nil,
+			startTimes: map[string]time.Time{},
+			expected:   nil,
+		},
+		{
+			name:     "already sorted",
+			channels: []*discordgo.Channel{channel("a", 0), channel("b", 1)},
+			startTimes: map[string]time.Time{
+				"a": now,
+				"b": now.Add(time.Hour),
+			},
+			expected: nil,
+		},
+		{
+			name:     "swapped events",
+			channels: []*discordgo.Channel{channel("a", 0), channel("b", 1)},
+			startTimes: map[string]time.Time{
+				"a": now.Add(time.Hour),
+				"b": now,
+			},
+			expected: []*discordgo.Channel{channel("b", 0), channel("a", 1)},
+		},
+		{
+			name:     "other channels keep their slots",
+			channels: []*discordgo.Channel{channel("rules", 0), channel("a", 1), channel("chat", 2), channel("b", 3)},
+			startTimes: map[string]time.Time{
+				"a": now.Add(time.Hour),
+				"b": now,
+			},
+			expected: []*discordgo.Channel{channel("b", 1), channel("a", 3)},
+		},
+		{
+			name:     "gaps and equal positions are closed up",
+			channels: []*discordgo.Channel{channel("b", 5), channel("a", 5), channel("chat", 9)},
+			startTimes: map[string]time.Time{
+				"a": now,
+				"b": now.Add(time.Hour),
+			},
+			expected: []*discordgo.Channel{channel("a", 0), channel("b", 1), channel("chat", 2)},
+		},
+		{
+			name:     "events starting at the same time keep their order",
+			channels: []*discordgo.Channel{channel("a", 0), channel("b", 1), channel("c", 2)},
+			startTimes: map[string]time.Time{
+				"a": now.Add(time.Hour),
+				"b": now,
+				"c": now,
+			},
+			expected: []*discordgo.Channel{channel("b", 0), channel("c", 1), channel("a", 2)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changed := getEventChannelOrder(test.channels, test.startTimes)
+			if !reflect.DeepEqual(changed, test.expected) {
+				t.Errorf("got %s, want %s", describePositions(changed), describePositions(test.expected))
+			}
+		})
+	}
+}
+
+func describePositions(channels []*discordgo.Channel) []string {
+	positions := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		positions = append(positions, fmt.Sprintf("%s@%d", channel.ID, channel.Position))
+	}
+
+	return positions
+}
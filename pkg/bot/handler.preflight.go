@@ -0,0 +1,240 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// What the bot can't create event channels without where they are created: creating them, setting their
+// overwrites, inviting to them and posting its own messages in them.
+const eventCategoryPermissions = discordgo.PermissionManageChannels |
+	discordgo.PermissionManageRoles |
+	discordgo.PermissionCreateInstantInvite |
+	eventBotPermissions
+
+// What the overwrites hand out to staff, hosts and participants on top. Discord only lets the bot grant permissions
+// it has, so lacking these is worth a warning but doesn't stop event channels from being created.
+const eventCategoryRecommendedPermissions = eventStaffPermissions &^ eventCategoryPermissions
+
+// Every permission. discordgo.PermissionAll predates the thread permissions, which owners and administrators have too.
+const allPermissions int64 = math.MaxInt64
+
+// What the bot needs in the announcement channel to post its announcements.
+const announcementChannelPermissions = discordgo.PermissionViewChannel |
+	discordgo.PermissionSendMessages |
+	discordgo.PermissionEmbedLinks
+
+// The names of the permissions the preflight checks, in the order they are listed.
+var preflightPermissionNames = []struct {
+	permission int64
+	name       string
+}{
+	{discordgo.PermissionViewChannel, "View Channel"},
+	{discordgo.PermissionManageChannels, "Manage Channels"},
+	{discordgo.PermissionManageRoles, "Manage Permissions"},
+	{discordgo.PermissionCreateInstantInvite, "Create Invite"},
+	{discordgo.PermissionSendMessages, "Send Messages"},
+	{discordgo.PermissionEmbedLinks, "Embed Links"},
+	{discordgo.PermissionAttachFiles, "Attach Files"},
+	{discordgo.PermissionAddReactions, "Add Reactions"},
+	{discordgo.PermissionReadMessageHistory, "Read Message History"},
+	{discordgo.PermissionManageMessages, "Manage Messages"},
+	{discordgo.PermissionManageThreads, "Manage Threads"},
+	{discordgo.PermissionCreatePublicThreads, "Create Public Threads"},
+	{discordgo.PermissionSendMessagesInThreads, "Send Messages in Threads"},
+}
+
+// Lists, one line per place, the permissions the bot lacks to create event channels and announce them with the
+// current settings of the Guild. Nothing is returned when the bot has everything it needs. Blocking tells whether
+// event channels can't be created at all, rather than only lacking some permissions for their members.
+func getMissingBotPermissions(s *discordgo.Session, guild *Guild) (problems []string, blocking bool, err error) {
+	var permissions int64
+	where := "the server"
+	if guild.EventChannelParentID != "" {
+		where = "the category " + describeChannel(s, guild.EventChannelParentID)
+		permissions, err = getBotChannelPermissions(s, guild.EventChannelParentID)
+	} else {
+		permissions, err = getBotGuildPermissions(s, guild.ID)
+	}
+	if err != nil {
+		if !isDiscordErrRESTCode(err, http.StatusNotFound) {
+			return nil, false, err
+		}
+		problems = append(problems, fmt.Sprintf("**%s** could not be found", where))
+		blocking = true
+	} else {
+		if missing := eventCategoryPermissions &^ permissions; missing != 0 {
+			problems = append(problems, fmt.Sprintf("**%s**: %s", where, formatPermissions(missing)))
+			blocking = true
+		}
+		if missing := eventCategoryRecommendedPermissions &^ permissions; missing != 0 {
+			problems = append(problems, fmt.Sprintf("**%s**: %s, so staff, hosts and participants can't be given them", where, formatPermissions(missing)))
+		}
+	}
+
+	if guild.EventAnnouncementChannelID != "" {
+		where = "the announcement channel " + describeChannel(s, guild.EventAnnouncementChannelID)
+		permissions, err = getBotChannelPermissions(s, guild.EventAnnouncementChannelID)
+		if err != nil {
+			if !isDiscordErrRESTCode(err, http.StatusNotFound) {
+				return nil, false, err
+			}
+			problems = append(problems, fmt.Sprintf("**%s** could not be found", where))
+			blocking = true
+		} else if missing := announcementChannelPermissions &^ permissions; missing != 0 {
+			// The setup fails and is rolled back when the announcement can't be posted.
+			problems = append(problems, fmt.Sprintf("**%s**: %s", where, formatPermissions(missing)))
+			blocking = true
+		}
+	}
+
+	return problems, blocking, nil
+}
+
+// Checks the permissions of the bot and tells the owner when they changed since the last check, so they hear about
+// each problem once instead of for every event. Returns the problems found and whether they keep event channels
+// from being created.
+func (em *EventManager) preflight(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guild *Guild) ([]string, bool, error) {
+	problems, blocking, err := getMissingBotPermissions(s, guild)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check permissions: %w", err)
+	}
+
+	reported := strings.Join(problems, "\n")
+	if reported == guild.PermissionProblems {
+		return problems, blocking, nil
+	}
+
+	guild.PermissionProblems = reported
+	_, err = em.engine.Context(ctx).ID(guild.ID).Cols("permission_problems").Update(guild)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(problems) == 0 {
+		log.Info("permission problems resolved")
+		return nil, false, nil
+	}
+
+	log.WithField("problems", reported).Warn("missing permissions")
+
+	err = em.notifyOwner(s, guild.ID, formatPermissionProblems(problems))
+	if err != nil {
+		log.WithError(err).Warn("failed to tell the owner about missing permissions")
+	}
+
+	return problems, blocking, nil
+}
+
+// Tells the discordgo.Guild owner about a problem, in the discordgo.Guild itself if their direct messages are closed.
+func (em *EventManager) notifyOwner(s *discordgo.Session, guildID string, content string) error {
+	guild, err := s.State.Guild(guildID)
+	if err != nil {
+		guild, err = s.Guild(guildID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = em.sendToOwner(s, guild, func(direct bool) *discordgo.MessageSend {
+		if direct {
+			return &discordgo.MessageSend{Content: fmt.Sprintf("**%s**: %s", guild.Name, content)}
+		}
+
+		return &discordgo.MessageSend{
+			Content:         fmt.Sprintf("<@%s> %s", guild.OwnerID, content),
+			AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{guild.OwnerID}},
+		}
+	})
+	return err
+}
+
+// The permissions of the bot in the discordgo.Channel, including the thread permissions when it is an administrator.
+func getBotChannelPermissions(s *discordgo.Session, channelID string) (int64, error) {
+	permissions, err := s.UserChannelPermissions(s.State.User.ID, channelID)
+	if err != nil {
+		return 0, err
+	}
+
+	if permissions&discordgo.PermissionAdministrator != 0 {
+		return allPermissions, nil
+	}
+
+	return permissions, nil
+}
+
+// The permissions granted to the bot by its roles, for when event channels are created outside of any category.
+func getBotGuildPermissions(s *discordgo.Session, guildID string) (int64, error) {
+	member, err := s.State.Member(guildID, s.State.User.ID)
+	if err != nil {
+		member, err = s.GuildMember(guildID, s.State.User.ID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return getMemberGuildPermissions(s, guildID, member)
+}
+
+// The permissions the roles of the discordgo.Member grant across the discordgo.Guild, ignoring channel overwrites.
+func getMemberGuildPermissions(s *discordgo.Session, guildID string, member *discordgo.Member) (int64, error) {
+	guild, err := s.State.Guild(guildID)
+	if err != nil {
+		guild, err = s.Guild(guildID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if member.User != nil && guild.OwnerID == member.User.ID {
+		return allPermissions, nil
+	}
+
+	roleIDs := map[string]bool{guildID: true}
+	for _, roleID := range member.Roles {
+		roleIDs[roleID] = true
+	}
+
+	var permissions int64
+	for _, role := range guild.Roles {
+		if roleIDs[role.ID] {
+			permissions |= role.Permissions
+		}
+	}
+
+	if permissions&discordgo.PermissionAdministrator != 0 {
+		return allPermissions, nil
+	}
+
+	return permissions, nil
+}
+
+func formatPermissionProblems(problems []string) string {
+	return "I am missing permissions to manage event channels:\n- " + strings.Join(problems, "\n- ")
+}
+
+func formatPermissions(permissions int64) string {
+	names := make([]string, 0, len(preflightPermissionNames))
+	for _, permission := range preflightPermissionNames {
+		if permissions&permission.permission != 0 {
+			names = append(names, permission.name)
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// Names the discordgo.Channel so it reads well in direct messages, where channel mentions don't resolve.
+func describeChannel(s *discordgo.Session, channelID string) string {
+	if channel := getStateChannel(s, channelID); channel != nil {
+		return "#" + channel.Name
+	}
+
+	return fmt.Sprintf("`%s`", channelID)
+}
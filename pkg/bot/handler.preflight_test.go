@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestGetMissingBotPermissions(t *testing.T) {
+	const (
+		categoryID     = "600000000000000001"
+		announcementID = "600000000000000002"
+		goneID         = "600000000000000003"
+	)
+
+	required := int64(eventCategoryPermissions)
+	everything := required | eventStaffPermissions
+
+	tests := []struct {
+		name      string
+		guild     *Guild
+		bot       int64
+		overwrite *discordgo.PermissionOverwrite
+		problems  []string
+		blocking  bool
+	}{
+		{
+			name:  "everything granted",
+			guild: &Guild{ID: testGuildID, EventChannelParentID: categoryID, EventAnnouncementChannelID: announcementID},
+			bot:   everything,
+		},
+		{
+			name:  "administrator",
+			guild: &Guild{ID: testGuildID},
+			bot:   discordgo.PermissionAdministrator,
+		},
+		{
+			name:     "only the staff permissions missing",
+			guild:    &Guild{ID: testGuildID},
+			bot:      required,
+			problems: []string{"**the server**: Attach Files"},
+			blocking: false,
+		},
+		{
+			name:     "no overwrites without Manage Permissions",
+			guild:    &Guild{ID: testGuildID},
+			bot:      everything &^ discordgo.PermissionManageRoles,
+			problems: []string{"**the server**: Manage Permissions"},
+			blocking: true,
+		},
+		{
+			name:      "category denies inviting",
+			guild:     &Guild{ID: testGuildID, EventChannelParentID: categoryID},
+			bot:       everything,
+			overwrite: &discordgo.PermissionOverwrite{ID: testGuildID, Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionCreateInstantInvite},
+			problems:  []string{"**the category #category**: Create Invite"},
+			blocking:  true,
+		},
+		{
+			name:     "category is gone",
+			guild:    &Guild{ID: testGuildID, EventChannelParentID: goneID},
+			bot:      everything,
+			problems: []string{"could not be found"},
+			blocking: true,
+		},
+		{
+			name:      "announcement channel denies posting",
+			guild:     &Guild{ID: testGuildID, EventAnnouncementChannelID: announcementID},
+			bot:       everything,
+			overwrite: &discordgo.PermissionOverwrite{ID: testGuildID, Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionSendMessages},
+			problems:  []string{"**the announcement channel #announcements**: Send Messages"},
+			blocking:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"code": 10003, "message": "Unknown Channel"}`))
+			}, test.bot)
+			s.MaxRestRetries = 0
+
+			var overwrites []*discordgo.PermissionOverwrite
+			if test.overwrite != nil {
+				overwrites = append(overwrites, test.overwrite)
+			}
+			for _, channel := range []*discordgo.Channel{
+				{ID: categoryID, GuildID: testGuildID, Name: "category", Type: discordgo.ChannelTypeGuildCategory, PermissionOverwrites: overwrites},
+				{ID: announcementID, GuildID: testGuildID, Name: "announcements", Type: discordgo.ChannelTypeGuildText, PermissionOverwrites: overwrites},
+			} {
+				if err := s.State.ChannelAdd(channel); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			problems, blocking, err := getMissingBotPermissions(s, test.guild)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(problems) != len(test.problems) {
+				t.Fatalf("got problems %q, want %q", problems, test.problems)
+			}
+			for idx, problem := range problems {
+				if !strings.Contains(problem, test.problems[idx]) {
+					t.Errorf("got problem %q, want it to contain %q", problem, test.problems[idx])
+				}
+			}
+			if blocking != test.blocking {
+				t.Errorf("got blocking %v, want %v", blocking, test.blocking)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// How long the data of a discordgo.Guild that removed the bot is kept, in case the bot is added back.
+const guildRetention = 30 * 24 * time.Hour
+
+// Whether the data of a Guild removed at deletedAt is still kept, a zero deletedAt means it was never removed.
+func isGuildRetained(deletedAt time.Time, now time.Time) bool {
+	return deletedAt.IsZero() || !deletedAt.Before(now.Add(-guildRetention))
+}
+
+// Brings back a Guild that removed the bot within the retention window. Guilds removed longer ago are purged so
+// they start over.
+func (em *EventManager) restoreGuild(ctx context.Context, log *logrus.Entry, guildID string) (bool, error) {
+	var guild Guild
+	found, err := em.engine.Context(ctx).Unscoped().ID(guildID).Get(&guild)
+	if err != nil {
+		return false, err
+	}
+	if !found || guild.DeletedAt.IsZero() {
+		return false, nil
+	}
+
+	if !isGuildRetained(guild.DeletedAt, time.Now()) {
+		return false, em.purgeGuild(ctx, guildID)
+	}
+
+	_, err = em.engine.Context(ctx).Unscoped().Table(&Guild{}).ID(guildID).Update(map[string]interface{}{"deleted_at": nil})
+	if err != nil {
+		return false, err
+	}
+
+	log.WithField("deleted_at", guild.DeletedAt).Info("restored guild")
+	return true, nil
+}
+
+// Drops the data of every Guild that removed the bot longer ago than the retention window.
+func (em *EventManager) purgeRemovedGuilds(ctx context.Context, log *logrus.Entry) error {
+	var guilds []*Guild
+	err := em.engine.Context(ctx).Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", time.Now().Add(-guildRetention)).Find(&guilds)
+	if err != nil {
+		return err
+	}
+
+	for _, guild := range guilds {
+		err = em.purgeGuild(ctx, guild.ID)
+		if err != nil {
+			log.WithError(err).WithField("guild_id", guild.ID).Error("failed to purge guild")
+			continue
+		}
+
+		log.WithField("guild_id", guild.ID).Info("purged guild")
+	}
+
+	return nil
+}
+
+func (em *EventManager) purgeGuild(ctx context.Context, guildID string) error {
+	_, err := em.engine.Context(ctx).Where("guild_id = ?", guildID).Delete(&Event{})
+	if err != nil {
+		return err
+	}
+
+	_, err = em.engine.Context(ctx).Where("guild_id = ?", guildID).Delete(&WizardSession{})
+	if err != nil {
+		return err
+	}
+
+	_, err = em.engine.Context(ctx).Where("guild_id = ?", guildID).Delete(&EventSetup{})
+	if err != nil {
+		return err
+	}
+
+	_, err = em.engine.Context(ctx).Unscoped().ID(guildID).Delete(&Guild{})
+	return err
+}
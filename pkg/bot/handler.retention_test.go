@@ -0,0 +1,30 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsGuildRetained(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		deletedAt time.Time
+		retained  bool
+	}{
+		{"never removed", time.Time{}, true},
+		{"just removed", now, true},
+		{"removed within the window", now.Add(-guildRetention + time.Hour), true},
+		{"removed exactly at the window", now.Add(-guildRetention), true},
+		{"removed before the window", now.Add(-guildRetention - time.Hour), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if retained := isGuildRetained(test.deletedAt, now); retained != test.retained {
+				t.Errorf("got %v, want %v", retained, test.retained)
+			}
+		})
+	}
+}
@@ -0,0 +1,427 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+type SetupStep = string
+
+const (
+	SetupStepAnnounceChannel SetupStep = "announce-channel"
+	SetupStepCategory        SetupStep = "category"
+	SetupStepDeleteWhenDone  SetupStep = "delete-when-done"
+	SetupStepMessage         SetupStep = "message"
+	SetupStepSync            SetupStep = "sync"
+	SetupStepDone            SetupStep = "done"
+)
+
+var setupSteps = []SetupStep{
+	SetupStepAnnounceChannel,
+	SetupStepCategory,
+	SetupStepDeleteWhenDone,
+	SetupStepMessage,
+	SetupStepSync,
+	SetupStepDone,
+}
+
+type SetupAction = string
+
+const (
+	SetupActionSelect SetupAction = "select"
+	SetupActionKeep   SetupAction = "keep"
+	SetupActionClear  SetupAction = "clear"
+	SetupActionYes    SetupAction = "yes"
+	SetupActionNo     SetupAction = "no"
+	SetupActionEdit   SetupAction = "edit"
+	SetupActionModal  SetupAction = "modal"
+	SetupActionLink   SetupAction = "link"
+	SetupActionCreate SetupAction = "create"
+)
+
+const setupComponentNamespace = "setup"
+
+const setupMessageInputID = "message"
+
+// Discord limits select menus to this many options.
+const maxSelectOptions = 25
+
+// The setup wizard has no session of its own, the SessionID carries the discordgo.Guild instead so the wizard also
+// works from the onboarding direct message.
+func (em *EventManager) setupCustomID(guildID string, userID string, step SetupStep, action SetupAction) string {
+	return em.encodeComponentID(componentID{
+		Namespace: setupComponentNamespace,
+		SessionID: guildID,
+		UserID:    userID,
+		Action:    action,
+		Arg:       step,
+	})
+}
+
+func nextSetupStep(step SetupStep) SetupStep {
+	for idx := range setupSteps {
+		if setupSteps[idx] == step && idx+1 < len(setupSteps) {
+			return setupSteps[idx+1]
+		}
+	}
+
+	return SetupStepDone
+}
+
+// Starts the setup wizard, or resumes it at the step the Guild last reached.
+func (em *EventManager) startSetup(ctx context.Context, s *discordgo.Session, guildID string, userID string) (*discordgo.InteractionResponseData, error) {
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(guildID).Get(&guild)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("could not find guild")
+	}
+
+	if guild.SetupStep == "" || guild.SetupStep == SetupStepDone {
+		guild.SetupStep = SetupStepAnnounceChannel
+		_, err = em.engine.Context(ctx).ID(guild.ID).Cols("setup_step").Update(&guild)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	content, components, err := em.getSetupStepMessage(s, &guild, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &discordgo.InteractionResponseData{
+		Content:    content,
+		Components: components,
+	}, nil
+}
+
+// Applies a button or select from the setup wizard and moves the message on to the next step.
+func (em *EventManager) handleSetupComponent(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, id componentID) error {
+	if i.Type == discordgo.InteractionModalSubmit {
+		return em.handleSetupModal(ctx, s, i, id)
+	}
+
+	step, action := id.Arg, id.Action
+
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(id.SessionID).Get(&guild)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("could not find guild")
+	}
+
+	if step != guild.SetupStep {
+		return em.respondSetupStep(s, i, &guild, "That step was already completed, here is where you left off.")
+	}
+
+	data := i.MessageComponentData()
+	switch step + ":" + action {
+	case SetupStepAnnounceChannel + ":" + SetupActionSelect:
+		if len(data.Values) != 1 {
+			return em.respondSetupStep(s, i, &guild, "No channel selected.")
+		}
+		guild.EventAnnouncementChannelID = data.Values[0]
+	case SetupStepAnnounceChannel + ":" + SetupActionClear:
+		guild.EventAnnouncementChannelID = ""
+	case SetupStepCategory + ":" + SetupActionSelect:
+		if len(data.Values) != 1 {
+			return em.respondSetupStep(s, i, &guild, "No category selected.")
+		}
+		guild.EventChannelParentID = data.Values[0]
+	case SetupStepCategory + ":" + SetupActionClear:
+		guild.EventChannelParentID = ""
+	case SetupStepDeleteWhenDone + ":" + SetupActionYes:
+		guild.DeleteWhenDone = true
+	case SetupStepDeleteWhenDone + ":" + SetupActionNo:
+		guild.DeleteWhenDone = false
+	case SetupStepMessage + ":" + SetupActionEdit:
+		return i.showModal(&discordgo.InteractionResponseData{
+			CustomID: em.setupCustomID(guild.ID, id.UserID, SetupStepMessage, SetupActionModal),
+			Title:    "Announcement message",
+			Components: []discordgo.MessageComponent{
+				&discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						&discordgo.TextInput{
+							CustomID:    setupMessageInputID,
+							Label:       "Use %EVENT% for the event name",
+							Style:       discordgo.TextInputParagraph,
+							Value:       guild.NewEventChannelMessage,
+							Placeholder: "`%EVENT%` was just created!",
+							Required:    true,
+							MaxLength:   255,
+						},
+					},
+				},
+			},
+		})
+	case SetupStepSync + ":" + SetupActionLink:
+		content, components, err := em.startSyncWizard(ctx, log, s, guild.ID, i.userID())
+		if err != nil {
+			return err
+		}
+
+		return i.update(&discordgo.InteractionResponseData{
+			Content:    content,
+			Components: components,
+		})
+	case SetupStepSync + ":" + SetupActionCreate:
+		err = i.deferResponse()
+		if err != nil {
+			return err
+		}
+
+		err = em.runInitialSync(ctx, log, s, guild.ID)
+		if err != nil {
+			return err
+		}
+
+		return i.update(&discordgo.InteractionResponseData{
+			Content:    "Setup complete! Channels were created for your existing events.",
+			Components: make([]discordgo.MessageComponent, 0),
+		})
+	case step + ":" + SetupActionKeep:
+	default:
+		return fmt.Errorf("unknown setup action %q for step %q", action, step)
+	}
+
+	return em.advanceSetup(ctx, s, i, &guild)
+}
+
+// Stores the announcement message template submitted through the setup modal.
+func (em *EventManager) handleSetupModal(ctx context.Context, s *discordgo.Session, i *interactionContext, id componentID) error {
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(id.SessionID).Get(&guild)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("could not find guild")
+	}
+
+	message := getModalValue(i.ModalSubmitData(), setupMessageInputID)
+	if message == "" {
+		return em.respondSetupStep(s, i, &guild, "The announcement message can't be empty.")
+	}
+
+	guild.NewEventChannelMessage = message
+	if guild.SetupStep != SetupStepMessage {
+		_, err = em.engine.Context(ctx).ID(guild.ID).Cols("new_event_channel_message").Update(&guild)
+		if err != nil {
+			return err
+		}
+
+		return em.respondSetupStep(s, i, &guild, "Announcement message updated.")
+	}
+
+	return em.advanceSetup(ctx, s, i, &guild)
+}
+
+// Persists the Guild at the next step so the wizard can be resumed, then shows that step.
+func (em *EventManager) advanceSetup(ctx context.Context, s *discordgo.Session, i *interactionContext, guild *Guild) error {
+	guild.SetupStep = nextSetupStep(guild.SetupStep)
+
+	// Everything needed to create channels is known once we reach the sync, so reconcile may run from here on.
+	if guild.SetupStep == SetupStepSync {
+		guild.ConfigurationWasRun = true
+		if guild.FirstReconcileRun {
+			guild.SetupStep = SetupStepDone
+		}
+	}
+
+	_, err := em.engine.Context(ctx).ID(guild.ID).UseBool().Update(guild)
+	if err != nil {
+		return err
+	}
+
+	return em.respondSetupStep(s, i, guild, "")
+}
+
+func (em *EventManager) respondSetupStep(s *discordgo.Session, i *interactionContext, guild *Guild, notice string) error {
+	content, components, err := em.getSetupStepMessage(s, guild, i.userID())
+	if err != nil {
+		return err
+	}
+
+	if notice != "" {
+		content = notice + "\n\n" + content
+	}
+
+	return i.update(&discordgo.InteractionResponseData{
+		Content:    content,
+		Components: components,
+	})
+}
+
+func (em *EventManager) getSetupStepMessage(s *discordgo.Session, guild *Guild, userID string) (string, []discordgo.MessageComponent, error) {
+	stepNumber := 1
+	for idx := range setupSteps {
+		if setupSteps[idx] == guild.SetupStep {
+			stepNumber = idx + 1
+		}
+	}
+	header := fmt.Sprintf("**Event Channels setup (%d/%d)**\n", stepNumber, len(setupSteps)-1)
+
+	switch guild.SetupStep {
+	case SetupStepAnnounceChannel:
+		options, err := getChannelSelectOptions(s, guild.ID, discordgo.ChannelTypeGuildText, guild.EventAnnouncementChannelID)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return header + "Which channel should new event channels be announced in?\n" +
+				"Only the first 25 channels are listed, use `/event-channels config set` for any other channel.",
+			em.withSetupSelect(guild.ID, userID, SetupStepAnnounceChannel, "Announcement channel", options, &discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					em.setupButton(guild.ID, userID, SetupStepAnnounceChannel, SetupActionKeep, "Keep current", discordgo.SecondaryButton, guild.EventAnnouncementChannelID == ""),
+					em.setupButton(guild.ID, userID, SetupStepAnnounceChannel, SetupActionClear, "Don't announce", discordgo.SecondaryButton, false),
+				},
+			}), nil
+	case SetupStepCategory:
+		options, err := getChannelSelectOptions(s, guild.ID, discordgo.ChannelTypeGuildCategory, guild.EventChannelParentID)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return header + "Which category should event channels be created in?",
+			em.withSetupSelect(guild.ID, userID, SetupStepCategory, "Event category", options, &discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					em.setupButton(guild.ID, userID, SetupStepCategory, SetupActionKeep, "Keep current", discordgo.SecondaryButton, guild.EventChannelParentID == ""),
+					em.setupButton(guild.ID, userID, SetupStepCategory, SetupActionClear, "No category", discordgo.SecondaryButton, false),
+				},
+			}), nil
+	case SetupStepDeleteWhenDone:
+		return header + "Should event channels be deleted once the event is over?",
+			[]discordgo.MessageComponent{
+				&discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						em.setupButton(guild.ID, userID, SetupStepDeleteWhenDone, SetupActionYes, "Delete when done", discordgo.DangerButton, false),
+						em.setupButton(guild.ID, userID, SetupStepDeleteWhenDone, SetupActionNo, "Keep channels", discordgo.PrimaryButton, false),
+					},
+				},
+			}, nil
+	case SetupStepMessage:
+		return header + "This message is posted in the announcement channel for every new event:\n>>> " + guild.NewEventChannelMessage,
+			[]discordgo.MessageComponent{
+				&discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						em.setupButton(guild.ID, userID, SetupStepMessage, SetupActionEdit, "Edit message", discordgo.PrimaryButton, false),
+						em.setupButton(guild.ID, userID, SetupStepMessage, SetupActionKeep, "Keep message", discordgo.SecondaryButton, false),
+					},
+				},
+			}, nil
+	case SetupStepSync:
+		return header + "Do you already have channels for your existing events?",
+			[]discordgo.MessageComponent{
+				&discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						em.setupButton(guild.ID, userID, SetupStepSync, SetupActionLink, "Link existing channels", discordgo.PrimaryButton, false),
+						em.setupButton(guild.ID, userID, SetupStepSync, SetupActionCreate, "Create new channels", discordgo.SecondaryButton, false),
+					},
+				},
+			}, nil
+	default:
+		problems, blocking, err := getMissingBotPermissions(s, guild)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(problems) > 0 && !blocking {
+			return "Setup complete! Some permissions are missing, event channels will be created without them.\n\n⚠️ " + formatPermissionProblems(problems),
+				make([]discordgo.MessageComponent, 0), nil
+		}
+		if len(problems) > 0 {
+			return "Setup complete! Channels will be created for new events once this is fixed.\n\n⚠️ " + formatPermissionProblems(problems),
+				make([]discordgo.MessageComponent, 0), nil
+		}
+
+		return "Setup complete! Channels will be created for new events.", make([]discordgo.MessageComponent, 0), nil
+	}
+}
+
+// Puts a select for the options above the buttons, leaving it out when there is nothing to choose from as
+// Discord rejects empty selects.
+func (em *EventManager) withSetupSelect(guildID string, userID string, step SetupStep, placeholder string, options []discordgo.SelectMenuOption, buttons *discordgo.ActionsRow) []discordgo.MessageComponent {
+	if len(options) == 0 {
+		return []discordgo.MessageComponent{buttons}
+	}
+
+	return []discordgo.MessageComponent{
+		&discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				&discordgo.SelectMenu{
+					CustomID:    em.setupCustomID(guildID, userID, step, SetupActionSelect),
+					Placeholder: placeholder,
+					MaxValues:   1,
+					Options:     options,
+				},
+			},
+		},
+		buttons,
+	}
+}
+
+func (em *EventManager) setupButton(guildID string, userID string, step SetupStep, action SetupAction, label string, style discordgo.ButtonStyle, disabled bool) discordgo.MessageComponent {
+	return &discordgo.Button{
+		CustomID: em.setupCustomID(guildID, userID, step, action),
+		Label:    label,
+		Style:    style,
+		Disabled: disabled,
+	}
+}
+
+// Lists the discordgo.Channel instances of a type in display order, marking the current one as the default.
+func getChannelSelectOptions(s *discordgo.Session, guildID string, channelType discordgo.ChannelType, currentID string) ([]discordgo.SelectMenuOption, error) {
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(channels, func(a, b int) bool {
+		return channels[a].Position < channels[b].Position
+	})
+
+	options := make([]discordgo.SelectMenuOption, 0, maxSelectOptions)
+	for _, channel := range channels {
+		if channel.Type != channelType {
+			continue
+		}
+		if len(options) == maxSelectOptions {
+			break
+		}
+
+		options = append(options, discordgo.SelectMenuOption{
+			Label:   channel.Name,
+			Value:   channel.ID,
+			Default: channel.ID == currentID,
+		})
+	}
+
+	return options, nil
+}
+
+func getModalValue(data discordgo.ModalSubmitInteractionData, customID string) string {
+	for _, component := range data.Components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+
+		for _, rowComponent := range row.Components {
+			input, ok := rowComponent.(*discordgo.TextInput)
+			if ok && input.CustomID == customID {
+				return strings.TrimSpace(input.Value)
+			}
+		}
+	}
+
+	return ""
+}
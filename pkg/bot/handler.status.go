@@ -0,0 +1,294 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const statusEventsPerPage = 10
+
+// Discord rejects embeds with a longer field value.
+const maxEmbedFieldValueLength = 1024
+
+// Describes the Guild configuration and one page of its tracked Events with any problems we can detect.
+func (em *EventManager) getStatus(ctx context.Context, s *discordgo.Session, guildID string, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.InteractionResponseData, error) {
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(guildID).Get(&guild)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("could not find guild")
+	}
+
+	page := 1
+	if options[CommandOptionPage] != nil {
+		page = int(options[CommandOptionPage].IntValue())
+	}
+
+	var events []*Event
+	err = em.engine.Context(ctx).Where("guild_id = ?", guildID).Asc("id").Find(&events)
+	if err != nil {
+		return nil, err
+	}
+
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	channelIDMap := map[string]*discordgo.Channel{}
+	for i := range channels {
+		channelIDMap[channels[i].ID] = channels[i]
+	}
+
+	scheduledEvents, err := s.GuildScheduledEvents(guildID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledEventMap := map[string]*discordgo.GuildScheduledEvent{}
+	for i := range scheduledEvents {
+		scheduledEventMap[scheduledEvents[i].ID] = scheduledEvents[i]
+	}
+
+	settings := &discordgo.MessageEmbed{
+		Title: "Event Channels configuration",
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Announcement channel", Value: statusChannel(guild.EventAnnouncementChannelID, channelIDMap), Inline: true},
+			{Name: "Category", Value: statusChannel(guild.EventChannelParentID, channelIDMap), Inline: true},
+			{Name: "Delete when done", Value: statusBool(guild.DeleteWhenDone), Inline: true},
+			{Name: "Manager role", Value: statusRole(guild.ManagerRoleID), Inline: true},
+			{Name: "Host roles", Value: statusRoles(guild.HostRoleIDs), Inline: true},
+			{Name: "Staff roles", Value: statusRoles(guild.StaffRoleIDs), Inline: true},
+			{Name: "Visibility", Value: statusPermissionProfile(guild.PermissionProfile), Inline: true},
+			{Name: "Participants", Value: statusParticipantPermissions(guild.ParticipantAllow, guild.ParticipantDeny), Inline: true},
+			{Name: "Deleted channels", Value: statusChannelDeletePolicy(guild.ChannelDeletePolicy), Inline: true},
+			{Name: "Manual edits", Value: statusChannelEditPolicy(guild.ChannelEditPolicy), Inline: true},
+			{Name: "Voice access", Value: statusBool(guild.VoiceAccess), Inline: true},
+			{Name: "Configuration run", Value: statusBool(guild.ConfigurationWasRun), Inline: true},
+			{Name: "Initial sync run", Value: statusBool(guild.FirstReconcileRun), Inline: true},
+			{Name: "Announcement message", Value: statusText(guild.NewEventChannelMessage)},
+			{Name: "Channel topic", Value: statusChannelTopic(guild.ChannelTopicTemplate)},
+		},
+	}
+
+	problems, _, err := getMissingBotPermissions(s, &guild)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		settings.Fields = append(settings.Fields, &discordgo.MessageEmbedField{
+			Name:  "⚠️ Missing permissions",
+			Value: "- " + strings.Join(problems, "\n- "),
+		})
+	}
+
+	// Role lists, the templates and the problems can all grow past what a field holds.
+	for _, field := range settings.Fields {
+		field.Value = truncateEmbedFieldValue(field.Value)
+	}
+
+	pages := (len(events) + statusEventsPerPage - 1) / statusEventsPerPage
+	if pages == 0 {
+		pages = 1
+	}
+	if page > pages {
+		page = pages
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	lines := make([]string, 0, statusEventsPerPage)
+	for _, event := range events[(page-1)*statusEventsPerPage : min(page*statusEventsPerPage, len(events))] {
+		name := event.ID
+		scheduledEvent, has := scheduledEventMap[event.ID]
+		if has {
+			name = scheduledEvent.Name
+		}
+
+		line := fmt.Sprintf("**%s** → %s", name, statusChannel(event.ChannelID, channelIDMap))
+		if problems := getEventProblems(&guild, event, scheduledEvent, channelIDMap); len(problems) > 0 {
+			line += "\n  ⚠️ " + strings.Join(problems, ", ")
+		}
+		lines = append(lines, line)
+	}
+
+	description := "No events are being tracked."
+	if len(lines) > 0 {
+		description = strings.Join(lines, "\n")
+	}
+
+	tracked := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Tracked events (%d)", len(events)),
+		Description: description,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Page %d of %d", page, pages),
+		},
+	}
+
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{settings, tracked},
+	}, nil
+}
+
+func getEventProblems(guild *Guild, event *Event, scheduledEvent *discordgo.GuildScheduledEvent, channelIDMap map[string]*discordgo.Channel) []string {
+	var problems []string
+
+	if scheduledEvent == nil {
+		problems = append(problems, "scheduled event no longer exists")
+	}
+
+	if event.Unlinked {
+		problems = append(problems, "channel was unlinked")
+		return problems
+	}
+
+	if event.ChannelID == "" {
+		problems = append(problems, "no channel recorded")
+		return problems
+	}
+
+	channel, has := channelIDMap[event.ChannelID]
+	if !has {
+		problems = append(problems, "channel is missing")
+		return problems
+	}
+
+	if guild.EventChannelParentID != "" && channel.ParentID != guild.EventChannelParentID {
+		problems = append(problems, "channel is outside the event category")
+	}
+
+	return problems
+}
+
+func statusChannel(channelID string, channelIDMap map[string]*discordgo.Channel) string {
+	if channelID == "" {
+		return "not set"
+	}
+
+	if _, has := channelIDMap[channelID]; !has {
+		return fmt.Sprintf("missing (`%s`)", channelID)
+	}
+
+	return fmt.Sprintf("<#%s>", channelID)
+}
+
+func statusRole(roleID string) string {
+	if roleID == "" {
+		return "not set"
+	}
+
+	return fmt.Sprintf("<@&%s>", roleID)
+}
+
+func statusRoles(roleIDs []string) string {
+	if len(roleIDs) == 0 {
+		return "not set"
+	}
+
+	mentions := make([]string, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		mentions = append(mentions, statusRole(roleID))
+	}
+
+	return strings.Join(mentions, ", ")
+}
+
+func statusPermissionProfile(profile PermissionProfile) string {
+	switch profile {
+	case PermissionProfilePublicReadOnly:
+		return "public read-only"
+	case PermissionProfilePublic:
+		return "public"
+	default:
+		return "private"
+	}
+}
+
+// Lists which participant permissions are allowed or denied, in the order of the permissions command options.
+func statusParticipantPermissions(allow int64, deny int64) string {
+	var allowed, denied []string
+	for _, option := range []CommandOption{CommandOptionSendMessages, CommandOptionAttachFiles, CommandOptionAddReactions, CommandOptionUseThreads} {
+		permissions := participantPermissionOptions[option]
+		if allow&permissions != 0 {
+			allowed = append(allowed, option)
+		}
+		if deny&permissions != 0 {
+			denied = append(denied, option)
+		}
+	}
+
+	if len(allowed) == 0 && len(denied) == 0 {
+		return "default"
+	}
+
+	var parts []string
+	if len(allowed) > 0 {
+		parts = append(parts, "allow "+strings.Join(allowed, ", "))
+	}
+	if len(denied) > 0 {
+		parts = append(parts, "deny "+strings.Join(denied, ", "))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func statusChannelDeletePolicy(policy ChannelDeletePolicy) string {
+	if policy == ChannelDeletePolicyUntrack {
+		return "stop tracking"
+	}
+
+	return "create again"
+}
+
+func statusChannelEditPolicy(policy ChannelEditPolicy) string {
+	if policy == ChannelEditPolicyRevert {
+		return "revert"
+	}
+
+	return "keep"
+}
+
+func statusBool(value bool) string {
+	if value {
+		return "yes"
+	}
+
+	return "no"
+}
+
+func statusText(value string) string {
+	if value == "" {
+		return "not set"
+	}
+
+	return value
+}
+
+// Shortens the value to fit an embed field, cutting at the last line or list separator so no mention is cut in half.
+func truncateEmbedFieldValue(value string) string {
+	runes := []rune(value)
+	if len(runes) <= maxEmbedFieldValueLength {
+		return value
+	}
+
+	truncated := string(runes[:maxEmbedFieldValueLength-1])
+	if i := strings.LastIndexAny(truncated, "\n,"); i > 0 {
+		truncated = truncated[:i]
+	}
+
+	return truncated + "…"
+}
+
+func statusChannelTopic(template string) string {
+	if template == "" {
+		return "default: " + defaultChannelTopicTemplate
+	}
+
+	return template
+}
@@ -0,0 +1,489 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// Creates channels for every discordgo.GuildScheduledEvent without an Event, fixes up the linked ones and marks
+// the Guild as synced so reconcile takes over.
+func (em *EventManager) runInitialSync(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guildID string) error {
+	events, err := s.GuildScheduledEvents(guildID, false)
+	if err != nil {
+		return err
+	}
+
+	var guild Guild
+	found, err := em.engine.Context(ctx).ID(guildID).Get(&guild)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return fmt.Errorf("could not find guild")
+	}
+
+	var internalEvents = map[string]*Event{}
+	err = em.engine.Context(ctx).Where("guild_id = ?", guildID).Find(&internalEvents)
+	if err != nil {
+		return err
+	}
+
+	atEveryoneRole, err := getAtEveryoneRole(s, guildID)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		internalEvent, found := internalEvents[event.ID]
+		if found && internalEvent.Unlinked {
+			continue
+		}
+
+		if !found {
+			err := em.onGuildEventCreate(ctx, log, s, &discordgo.GuildScheduledEventCreate{
+				GuildScheduledEvent: event,
+			})
+			if err != nil {
+				return err
+			}
+		} else if internalEvent.ChannelID == "" {
+			// The wizard asked for a new channel, the Event keeps its co-hosts and members.
+			_, err := em.recreateEventChannel(ctx, log, s, &guild, internalEvent, event)
+			if err != nil {
+				return err
+			}
+		} else {
+			permissionOverwrites, err := getEventPermissionOverwrites(s, &guild, event, internalEvent, atEveryoneRole)
+			if err != nil {
+				return err
+			}
+
+			_, err = em.editEventChannel(ctx, s, internalEvent, &discordgo.ChannelEdit{
+				Name:                 eventChannelName(event.Name),
+				ParentID:             guild.EventChannelParentID,
+				PermissionOverwrites: permissionOverwrites,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = em.updateEventInfoMessage(ctx, log, s, guildID, internalEvent.ID)
+			if err != nil {
+				log.WithError(err).Warn("failed to post info message")
+			}
+		}
+	}
+
+	err = em.orderEventChannels(ctx, log, s, &guild)
+	if err != nil {
+		log.WithError(err).Warn("failed to order event channels")
+	}
+
+	guild.FirstReconcileRun = true
+	if guild.SetupStep == SetupStepSync {
+		guild.SetupStep = SetupStepDone
+	}
+	_, err = em.engine.Context(ctx).ID(guild.ID).UseBool().Update(&guild)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type SyncAction = string
+
+const (
+	SyncActionSelect SyncAction = "select"
+	SyncActionSearch SyncAction = "search"
+	SyncActionFilter SyncAction = "filter"
+	SyncActionPrev   SyncAction = "prev"
+	SyncActionNext   SyncAction = "next"
+	SyncActionDone   SyncAction = "done"
+)
+
+const syncComponentNamespace = "sync"
+
+const syncSearchInputID = "search"
+
+// The selects, the search buttons and the navigation buttons must fit in the 5 rows Discord allows per message.
+const syncEventsPerPage = 3
+
+// Value of the select option asking for a new channel, as Discord does not allow empty option values.
+const syncNewChannelValue = "new"
+
+// Wizard sessions that haven't been touched for this long are rejected and purged.
+const wizardSessionTTL = 30 * time.Minute
+
+func (em *EventManager) syncCustomID(session *WizardSession, action SyncAction, eventID string) string {
+	return em.encodeComponentID(componentID{
+		Namespace: syncComponentNamespace,
+		SessionID: session.ID,
+		UserID:    session.UserID,
+		Action:    action,
+		Arg:       eventID,
+	})
+}
+
+// Creates a WizardSession seeded with the channels already linked to events and renders its first page.
+func (em *EventManager) startSyncWizard(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guildID string, userID string) (string, []discordgo.MessageComponent, error) {
+	_, err := em.engine.Context(ctx).Where("updated_at < ?", time.Now().Add(-wizardSessionTTL)).Delete(&WizardSession{})
+	if err != nil {
+		log.WithError(err).Warn("failed to purge expired wizard sessions")
+	}
+
+	var events []*Event
+	err = em.engine.Context(ctx).Where("guild_id = ? AND unlinked = ?", guildID, false).Find(&events)
+	if err != nil {
+		return "", nil, err
+	}
+
+	session := &WizardSession{
+		ID:         newSessionID(),
+		GuildID:    guildID,
+		UserID:     userID,
+		Selections: map[string]string{},
+		Filters:    map[string]string{},
+	}
+	for _, event := range events {
+		session.Selections[event.ID] = event.ChannelID
+	}
+
+	_, err = em.engine.Context(ctx).Insert(session)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return em.renderSyncWizard(s, session)
+}
+
+// Applies a select, search, page or done interaction to the WizardSession named in the CustomID.
+func (em *EventManager) handleSyncComponent(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, id componentID) error {
+	session := &WizardSession{ID: id.SessionID}
+	found, err := em.engine.Context(ctx).Get(session)
+	if err != nil {
+		return err
+	}
+	// Wizards started from the onboarding direct message have no discordgo.Guild in their interactions.
+	if !found || (i.GuildID != "" && session.GuildID != i.GuildID) || session.UserID != id.UserID {
+		return i.respond(&discordgo.InteractionResponseData{
+			Content: "This sync was already finished or has expired, run the sync command again.",
+		})
+	}
+
+	if time.Since(session.UpdatedAt) > wizardSessionTTL {
+		_, err = em.engine.Context(ctx).Delete(&WizardSession{ID: session.ID})
+		if err != nil {
+			log.WithError(err).Warn("failed to delete expired wizard session")
+		}
+
+		return i.respond(&discordgo.InteractionResponseData{
+			Content: "This sync was already finished or has expired, run the sync command again.",
+		})
+	}
+
+	action, eventID := id.Action, id.Arg
+	switch action {
+	case SyncActionSelect:
+		data := i.MessageComponentData()
+		if len(data.Values) != 1 {
+			return i.respond(&discordgo.InteractionResponseData{
+				Content: "No value selected",
+			})
+		}
+
+		channelID := data.Values[0]
+		if channelID == syncNewChannelValue {
+			channelID = ""
+		}
+		// A channel belongs to a single event, the one it was taken from gets a new channel instead.
+		for otherEventID, otherChannelID := range session.Selections {
+			if channelID != "" && otherEventID != eventID && otherChannelID == channelID {
+				session.Selections[otherEventID] = ""
+			}
+		}
+		session.Selections[eventID] = channelID
+	case SyncActionSearch:
+		return i.showModal(&discordgo.InteractionResponseData{
+			CustomID: em.syncCustomID(session, SyncActionFilter, eventID),
+			Title:    "Search channels",
+			Components: []discordgo.MessageComponent{
+				&discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						&discordgo.TextInput{
+							CustomID:  syncSearchInputID,
+							Label:     "Part of the channel name, empty for all",
+							Style:     discordgo.TextInputShort,
+							Value:     session.Filters[eventID],
+							Required:  false,
+							MaxLength: 100,
+						},
+					},
+				},
+			},
+		})
+	case SyncActionFilter:
+		session.Filters[eventID] = getModalValue(i.ModalSubmitData(), syncSearchInputID)
+	case SyncActionPrev:
+		session.Page--
+	case SyncActionNext:
+		session.Page++
+	case SyncActionDone:
+		return em.finishSyncWizard(ctx, log, s, i, session)
+	default:
+		return fmt.Errorf("unknown sync action %q", action)
+	}
+
+	_, err = em.engine.Context(ctx).ID(session.ID).AllCols().Update(session)
+	if err != nil {
+		return err
+	}
+
+	content, components, err := em.renderSyncWizard(s, session)
+	if err != nil {
+		return err
+	}
+
+	return i.update(&discordgo.InteractionResponseData{
+		Content:    content,
+		Components: components,
+	})
+}
+
+// Stores the selections as Events, creates channels for everything else and drops the WizardSession.
+func (em *EventManager) finishSyncWizard(ctx context.Context, log *logrus.Entry, s *discordgo.Session, i *interactionContext, session *WizardSession) error {
+	err := i.update(&discordgo.InteractionResponseData{
+		Content:    "finishing up...",
+		Components: make([]discordgo.MessageComponent, 0),
+	})
+	if err != nil {
+		return err
+	}
+
+	selected := map[string]bool{}
+	for _, channelID := range session.Selections {
+		selected[channelID] = true
+	}
+
+	for eventID, channelID := range session.Selections {
+		event := &Event{ID: eventID}
+		has, err := em.engine.Context(ctx).Get(event)
+		if err != nil {
+			return err
+		}
+
+		if has && event.ChannelID != channelID {
+			// Deleting the previous channel is only safe once no event points at it anymore.
+			previousChannelID := event.ChannelID
+			err = em.releaseSyncedEventChannel(ctx, log, s, event, channelID)
+			if err != nil {
+				return err
+			}
+
+			if channelID == "" && previousChannelID != "" && !selected[previousChannelID] {
+				_, err = s.ChannelDelete(previousChannelID)
+				if err != nil && !isDiscordErrRESTCode(err, http.StatusNotFound) {
+					log.WithError(err).Warn("failed to delete previous event channel")
+				}
+			}
+			continue
+		}
+
+		if !has && channelID != "" {
+			event.GuildID = session.GuildID
+			event.ChannelID = channelID
+			_, err = em.engine.Context(ctx).Insert(event)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = em.runInitialSync(ctx, log, s, session.GuildID)
+	if err != nil {
+		return err
+	}
+
+	_, err = em.engine.Context(ctx).Delete(&WizardSession{ID: session.ID})
+	if err != nil {
+		log.WithError(err).Warn("failed to delete wizard session")
+	}
+
+	return i.update(&discordgo.InteractionResponseData{
+		Content: "Channels linked!",
+	})
+}
+
+// Points the Event at the channel picked in the wizard, empty when a new one has to be created, dropping the invite,
+// info message and locks that belonged to its previous channel.
+func (em *EventManager) releaseSyncedEventChannel(ctx context.Context, log *logrus.Entry, s *discordgo.Session, event *Event, channelID string) error {
+	if event.InviteCode != "" {
+		err := deleteEventInvite(s, event.InviteCode)
+		if err != nil {
+			log.WithError(err).Warn("failed to delete previous invite")
+		}
+	}
+
+	if event.ChannelID != "" && event.InfoMessageID != "" {
+		err := s.ChannelMessageDelete(event.ChannelID, event.InfoMessageID)
+		if err != nil && !isDiscordErrRESTCode(err, http.StatusNotFound) {
+			log.WithError(err).Warn("failed to delete previous info message")
+		}
+	}
+
+	event.ChannelID = channelID
+	event.Unlinked = false
+	event.InviteCode = ""
+	event.InviteExpiresAt = nil
+	event.InfoMessageID = ""
+	event.NameLocked = false
+	event.PermissionsLocked = false
+	event.SentChannelName = ""
+	event.ChannelName = ""
+	_, err := em.engine.Context(ctx).ID(event.ID).Cols("channel_id", "unlinked", "invite_code", "invite_expires_at", "info_message_id", "name_locked", "permissions_locked", "sent_channel_name", "channel_name").Update(event)
+	return err
+}
+
+// Renders the current page of the WizardSession: one select per event, a search button per event and navigation.
+func (em *EventManager) renderSyncWizard(s *discordgo.Session, session *WizardSession) (string, []discordgo.MessageComponent, error) {
+	events, err := em.getCachedScheduledEvents(s, session.GuildID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(events) == 0 {
+		return "You don't have any events to sync! You're ready to start creating events!", make([]discordgo.MessageComponent, 0), nil
+	}
+
+	events = append([]*discordgo.GuildScheduledEvent(nil), events...)
+	sort.SliceStable(events, func(a, b int) bool {
+		return events[a].ScheduledStartTime.Before(events[b].ScheduledStartTime)
+	})
+
+	channels, err := s.GuildChannels(session.GuildID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sort.SliceStable(channels, func(a, b int) bool {
+		return channels[a].Position < channels[b].Position
+	})
+
+	pages := (len(events) + syncEventsPerPage - 1) / syncEventsPerPage
+	if session.Page >= pages {
+		session.Page = pages - 1
+	}
+	if session.Page < 0 {
+		session.Page = 0
+	}
+
+	pageEvents := events[session.Page*syncEventsPerPage : min((session.Page+1)*syncEventsPerPage, len(events))]
+
+	components := make([]discordgo.MessageComponent, 0, syncEventsPerPage+2)
+	searchButtons := make([]discordgo.MessageComponent, 0, syncEventsPerPage)
+	for _, event := range pageEvents {
+		components = append(components, &discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				&discordgo.SelectMenu{
+					CustomID:    em.syncCustomID(session, SyncActionSelect, event.ID),
+					Placeholder: truncateChoiceName(event.Name),
+					MaxValues:   1,
+					Options:     getSyncChannelOptions(channels, session.Selections[event.ID], session.Filters[event.ID]),
+				},
+			},
+		})
+
+		label := "Search: " + event.Name
+		if filter := session.Filters[event.ID]; filter != "" {
+			label = fmt.Sprintf("%q: %s", filter, event.Name)
+		}
+		searchButtons = append(searchButtons, &discordgo.Button{
+			CustomID: em.syncCustomID(session, SyncActionSearch, event.ID),
+			Label:    truncateButtonLabel(label),
+			Style:    discordgo.SecondaryButton,
+		})
+	}
+
+	components = append(components,
+		&discordgo.ActionsRow{
+			Components: searchButtons,
+		},
+		&discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				&discordgo.Button{
+					CustomID: em.syncCustomID(session, SyncActionPrev, ""),
+					Label:    "Previous",
+					Style:    discordgo.SecondaryButton,
+					Disabled: session.Page == 0,
+				},
+				&discordgo.Button{
+					CustomID: em.syncCustomID(session, SyncActionNext, ""),
+					Label:    "Next",
+					Style:    discordgo.SecondaryButton,
+					Disabled: session.Page == pages-1,
+				},
+				&discordgo.Button{
+					CustomID: em.syncCustomID(session, SyncActionDone, ""),
+					Label:    "Done",
+					Style:    discordgo.SuccessButton,
+				},
+			},
+		},
+	)
+
+	content := "Select the channels you want to assign to these already existing events.\n" +
+		"If no channel is selected for an event, one will be created.\n" +
+		"Channels selected here will be made private, renamed, and the users marked as interested will be given access.\n" +
+		fmt.Sprintf("Page %d of %d, %d events.", session.Page+1, pages, len(events))
+
+	return content, components, nil
+}
+
+// Lists the "new channel" choice, the current selection and as many text channels matching the filter as fit.
+func getSyncChannelOptions(channels []*discordgo.Channel, selectedID string, filter string) []discordgo.SelectMenuOption {
+	options := make([]discordgo.SelectMenuOption, 0, maxSelectOptions)
+	options = append(options, discordgo.SelectMenuOption{
+		Label:   "Create a new channel",
+		Value:   syncNewChannelValue,
+		Default: selectedID == "",
+	})
+
+	for _, channel := range channels {
+		if channel.ID == selectedID {
+			options = append(options, discordgo.SelectMenuOption{
+				Label:   "#" + channel.Name,
+				Value:   channel.ID,
+				Default: true,
+			})
+			break
+		}
+	}
+
+	filter = strings.ToLower(filter)
+	for _, channel := range channels {
+		if len(options) == maxSelectOptions {
+			break
+		}
+		if channel.Type != discordgo.ChannelTypeGuildText || channel.ID == selectedID {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(channel.Name), filter) {
+			continue
+		}
+
+		options = append(options, discordgo.SelectMenuOption{
+			Label: "#" + channel.Name,
+			Value: channel.ID,
+		})
+	}
+
+	return options
+}
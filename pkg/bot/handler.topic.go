@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// How long updates to an event have to settle before its channel is edited, Discord only allows a couple of
+// name and topic edits per channel every few minutes.
+const channelUpdateDelay = 10 * time.Second
+
+// Where the event takes place, the external location or the voice or stage channel.
+func getEventLocation(scheduledEvent *discordgo.GuildScheduledEvent) string {
+	switch scheduledEvent.EntityType {
+	case discordgo.GuildScheduledEventEntityTypeExternal:
+		return scheduledEvent.EntityMetadata.Location
+	case discordgo.GuildScheduledEventEntityTypeVoice, discordgo.GuildScheduledEventEntityTypeStageInstance:
+		if scheduledEvent.ChannelID != "" {
+			return fmt.Sprintf("<#%s>", scheduledEvent.ChannelID)
+		}
+	}
+
+	return ""
+}
+
+// Returns the edit bringing the name and topic of the discordgo.Channel in line with the event, nil when they
+// already are.
+func getEventChannelEdit(guild *Guild, event *Event, scheduledEvent *discordgo.GuildScheduledEvent, channel *discordgo.Channel) *discordgo.ChannelEdit {
+	edit := &discordgo.ChannelEdit{
+		Position: channel.Position,
+	}
+	changed := false
+
+	// Discord may store a different name than the one sent, so the name is only sent again when the event was renamed
+	// or the channel no longer has the name Discord made of it. Channels without a recorded name are renamed once to
+	// record it.
+	name := eventChannelName(scheduledEvent.Name)
+	if !event.NameLocked && (event.ChannelName == "" || name != event.SentChannelName || channel.Name != event.ChannelName) {
+		edit.Name = name
+		changed = true
+	}
+
+	// discordgo leaves an empty topic out of the edit, so a template rendering nothing can't clear it.
+	if topic := guild.GetEventChannelTopic(scheduledEvent); topic != "" && topic != channel.Topic {
+		edit.Topic = topic
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return edit
+}
+
+// Updates the discordgo.Channel of the event once no other update for it came in for channelUpdateDelay.
+func (em *EventManager) scheduleEventChannelUpdate(log *logrus.Entry, s *discordgo.Session, guildID string, eventID string) {
+	em.channelUpdates.schedule(eventID, func() {
+		err := em.updateEventChannel(context.Background(), log, s, guildID, eventID)
+		if err != nil {
+			log.WithError(err).Error("failed to update event channel")
+		}
+	})
+}
+
+// Schedules an update for the discordgo.Channel of every tracked event of the discordgo.Guild.
+func (em *EventManager) scheduleGuildEventChannelUpdates(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guildID string) error {
+	var events []*Event
+	err := em.engine.Context(ctx).Where("guild_id = ? AND unlinked = ? AND channel_id <> ''", guildID, false).Find(&events)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		em.scheduleEventChannelUpdate(log.WithField("event_id", event.ID), s, guildID, event.ID)
+	}
+
+	return nil
+}
+
+// Brings the name, topic, position, voice access and info message of the discordgo.Channel in line with the current state of the event.
+func (em *EventManager) updateEventChannel(ctx context.Context, log *logrus.Entry, s *discordgo.Session, guildID string, eventID string) error {
+	guild, event, err := em.getGuildAndEvent(ctx, guildID, eventID)
+	if err != nil {
+		return err
+	}
+
+	if event == nil || event.ChannelID == "" {
+		return nil
+	}
+
+	scheduledEvent, err := s.GuildScheduledEvent(guildID, eventID, false)
+	if err != nil {
+		if isDiscordErrRESTCode(err, http.StatusNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get scheduled event: %w", err)
+	}
+
+	channel, err := s.Channel(event.ChannelID)
+	if err != nil {
+		if isDiscordErrRESTCode(err, http.StatusNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get channel: %w", err)
+	}
+
+	if edit := getEventChannelEdit(guild, event, scheduledEvent, channel); edit != nil {
+		_, err = em.editEventChannel(ctx, s, event, edit)
+		if err != nil {
+			return fmt.Errorf("failed to update channel: %w", err)
+		}
+
+		log.WithField("channel_id", channel.ID).Debug("updated event channel")
+	}
+
+	// The event may have moved to another voice or stage channel.
+	err = em.syncEventVoiceAccess(ctx, s, guild, event, scheduledEvent)
+	if err != nil {
+		log.WithError(err).Warn("failed to update voice access")
+	}
+
+	err = em.updateEventInfoMessage(ctx, log, s, guildID, eventID)
+	if err != nil {
+		log.WithError(err).Warn("failed to update info message")
+	}
+
+	// The event may have been rescheduled.
+	return em.orderEventChannels(ctx, log, s, guild)
+}
@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestGetEventChannelEdit(t *testing.T) {
+	guild := &Guild{ID: testGuildID, ChannelTopicTemplate: TopicPlaceholderEvent}
+	scheduledEvent := &discordgo.GuildScheduledEvent{Name: "Game Night", ScheduledStartTime: time.Unix(1700000000, 0)}
+
+	tests := []struct {
+		name    string
+		event   *Event
+		channel *discordgo.Channel
+		edit    *discordgo.ChannelEdit
+	}{
+		{
+			name:    "up to date",
+			event:   &Event{SentChannelName: "game-night", ChannelName: "game-night"},
+			channel: &discordgo.Channel{Name: "game-night", Topic: "Game Night"},
+			edit:    nil,
+		},
+		{
+			// Discord may store another name than the one sent, it must not be sent again on every update.
+			name:    "normalized by Discord",
+			event:   &Event{SentChannelName: "game-night", ChannelName: "gamenight"},
+			channel: &discordgo.Channel{Name: "gamenight", Topic: "Game Night"},
+			edit:    nil,
+		},
+		{
+			name:    "renamed event",
+			event:   &Event{SentChannelName: "board-games", ChannelName: "board-games"},
+			channel: &discordgo.Channel{Name: "board-games", Topic: "Game Night"},
+			edit:    &discordgo.ChannelEdit{Name: "game-night"},
+		},
+		{
+			name:    "renamed channel",
+			event:   &Event{SentChannelName: "game-night", ChannelName: "game-night"},
+			channel: &discordgo.Channel{Name: "lobby", Topic: "Game Night"},
+			edit:    &discordgo.ChannelEdit{Name: "game-night"},
+		},
+		{
+			// Rows stored before the names were recorded are renamed once to record them.
+			name:    "legacy row",
+			event:   &Event{},
+			channel: &discordgo.Channel{Name: "game-night", Topic: "Game Night"},
+			edit:    &discordgo.ChannelEdit{Name: "game-night"},
+		},
+		{
+			name:    "name locked",
+			event:   &Event{NameLocked: true, SentChannelName: "board-games", ChannelName: "board-games"},
+			channel: &discordgo.Channel{Name: "lobby", Topic: "Game Night"},
+			edit:    nil,
+		},
+		{
+			name:    "outdated topic",
+			event:   &Event{NameLocked: true},
+			channel: &discordgo.Channel{Name: "lobby", Topic: "Board Games", Position: 3},
+			edit:    &discordgo.ChannelEdit{Topic: "Game Night"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			edit := getEventChannelEdit(guild, test.event, scheduledEvent, test.channel)
+			if test.edit == nil {
+				if edit != nil {
+					t.Errorf("got %+v, want no edit", edit)
+				}
+				return
+			}
+
+			if edit == nil {
+				t.Fatalf("got no edit, want %+v", test.edit)
+			}
+			if edit.Name != test.edit.Name || edit.Topic != test.edit.Topic || edit.Position != test.channel.Position {
+				t.Errorf("got name %q, topic %q and position %d, want %q, %q and %d", edit.Name, edit.Topic, edit.Position, test.edit.Name, test.edit.Topic, test.channel.Position)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// What interested users get on a private voice or stage channel when the Guild grants voice access.
+const voiceAccessPermissions = discordgo.PermissionViewChannel | discordgo.PermissionVoiceConnect
+
+// The voice or stage discordgo.Channel the event takes place in, empty for external events.
+func getEventVoiceChannelID(scheduledEvent *discordgo.GuildScheduledEvent) string {
+	switch scheduledEvent.EntityType {
+	case discordgo.GuildScheduledEventEntityTypeVoice, discordgo.GuildScheduledEventEntityTypeStageInstance:
+		return scheduledEvent.ChannelID
+	}
+
+	return ""
+}
+
+// Whether @everyone can't see or join the voice discordgo.Channel, so interested users need to be let in.
+func isPrivateVoiceChannel(channel *discordgo.Channel) bool {
+	for _, overwrite := range channel.PermissionOverwrites {
+		if overwrite.Type == discordgo.PermissionOverwriteTypeRole && overwrite.ID == channel.GuildID {
+			return overwrite.Deny&voiceAccessPermissions != 0
+		}
+	}
+
+	return false
+}
+
+func getMemberOverwrite(channel *discordgo.Channel, userID string) *discordgo.PermissionOverwrite {
+	for _, overwrite := range channel.PermissionOverwrites {
+		if overwrite.Type == discordgo.PermissionOverwriteTypeMember && overwrite.ID == userID {
+			return overwrite
+		}
+	}
+
+	return &discordgo.PermissionOverwrite{ID: userID, Type: discordgo.PermissionOverwriteTypeMember}
+}
+
+// Lets the user into the private voice or stage discordgo.Channel of the event, remembering the grant so it is only
+// taken back from users who did not have access on their own.
+func (em *EventManager) grantVoiceAccess(ctx context.Context, s *discordgo.Session, guild *Guild, event *Event, scheduledEvent *discordgo.GuildScheduledEvent, userID string) error {
+	voiceChannelID := getEventVoiceChannelID(scheduledEvent)
+	if !guild.VoiceAccess || voiceChannelID == "" {
+		return nil
+	}
+
+	channel, err := s.Channel(voiceChannelID)
+	if err != nil {
+		return fmt.Errorf("failed to get voice channel: %w", err)
+	}
+
+	return em.grantVoiceChannelAccess(ctx, s, event, channel, userID)
+}
+
+// Lets the user into the voice discordgo.Channel already fetched for the event, see grantVoiceAccess.
+func (em *EventManager) grantVoiceChannelAccess(ctx context.Context, s *discordgo.Session, event *Event, channel *discordgo.Channel, userID string) error {
+	if event.VoiceChannelID != channel.ID && len(event.VoiceAccessIDs) > 0 {
+		err := em.revokeAllVoiceAccess(ctx, s, event)
+		if err != nil {
+			return err
+		}
+	}
+
+	if containsString(event.VoiceAccessIDs, userID) {
+		return nil
+	}
+
+	if !isPrivateVoiceChannel(channel) {
+		return nil
+	}
+
+	overwrite := getMemberOverwrite(channel, userID)
+	if overwrite.Allow&voiceAccessPermissions == voiceAccessPermissions {
+		return nil
+	}
+
+	err := s.ChannelPermissionSet(channel.ID, userID, discordgo.PermissionOverwriteTypeMember, overwrite.Allow|voiceAccessPermissions, overwrite.Deny&^voiceAccessPermissions)
+	if err != nil {
+		return fmt.Errorf("failed to grant voice access: %w", err)
+	}
+
+	return em.modifyEvent(ctx, event, func(event *Event) bool {
+		if event.VoiceChannelID == channel.ID && containsString(event.VoiceAccessIDs, userID) {
+			return false
+		}
+
+		if event.VoiceChannelID != channel.ID {
+			event.VoiceAccessIDs = nil
+		}
+		event.VoiceChannelID = channel.ID
+		event.VoiceAccessIDs = append(event.VoiceAccessIDs, userID)
+		return true
+	}, "voice_channel_id", "voice_access_ids")
+}
+
+// Takes back the voice access the bot granted the user, leaving whatever else their overwrite holds.
+func (em *EventManager) revokeVoiceAccess(ctx context.Context, s *discordgo.Session, event *Event, userID string) error {
+	if !containsString(event.VoiceAccessIDs, userID) {
+		return nil
+	}
+
+	err := removeVoiceAccess(s, event.VoiceChannelID, userID)
+	if err != nil {
+		return err
+	}
+
+	return em.modifyEvent(ctx, event, func(event *Event) bool {
+		event.VoiceAccessIDs = removeString(event.VoiceAccessIDs, userID)
+		return true
+	}, "voice_access_ids")
+}
+
+// Takes back every voice access the bot granted for the event, when it ended or moved to another channel.
+func (em *EventManager) revokeAllVoiceAccess(ctx context.Context, s *discordgo.Session, event *Event) error {
+	voiceChannelID, userIDs := event.VoiceChannelID, event.VoiceAccessIDs
+	for _, userID := range userIDs {
+		err := removeVoiceAccess(s, voiceChannelID, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Only forget the grants that were taken back, others may have been added meanwhile.
+	return em.modifyEvent(ctx, event, func(event *Event) bool {
+		if event.VoiceChannelID != voiceChannelID {
+			return false
+		}
+
+		for _, userID := range userIDs {
+			event.VoiceAccessIDs = removeString(event.VoiceAccessIDs, userID)
+		}
+		if len(event.VoiceAccessIDs) == 0 {
+			event.VoiceChannelID = ""
+		}
+		return true
+	}, "voice_channel_id", "voice_access_ids")
+}
+
+func removeVoiceAccess(s *discordgo.Session, channelID string, userID string) error {
+	channel, err := s.Channel(channelID)
+	if err != nil {
+		if isDiscordErrRESTCode(err, http.StatusNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get voice channel: %w", err)
+	}
+
+	overwrite := getMemberOverwrite(channel, userID)
+	allow := overwrite.Allow &^ voiceAccessPermissions
+	if allow == 0 && overwrite.Deny == 0 {
+		err = s.ChannelPermissionDelete(channel.ID, userID)
+	} else {
+		err = s.ChannelPermissionSet(channel.ID, userID, discordgo.PermissionOverwriteTypeMember, allow, overwrite.Deny)
+	}
+	if err != nil && !isDiscordErrRESTCode(err, http.StatusNotFound) {
+		return fmt.Errorf("failed to revoke voice access: %w", err)
+	}
+
+	return nil
+}
+
+// Brings the voice access in line with the interested users, after the event moved to another channel, the Guild
+// changed its setting or while the bot was not listening.
+func (em *EventManager) syncEventVoiceAccess(ctx context.Context, s *discordgo.Session, guild *Guild, event *Event, scheduledEvent *discordgo.GuildScheduledEvent) error {
+	voiceChannelID := getEventVoiceChannelID(scheduledEvent)
+	if (!guild.VoiceAccess || voiceChannelID != event.VoiceChannelID) && len(event.VoiceAccessIDs) > 0 {
+		err := em.revokeAllVoiceAccess(ctx, s, event)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !guild.VoiceAccess || voiceChannelID == "" {
+		return nil
+	}
+
+	// Everyone can already join a channel that is not private, there is nothing to grant.
+	channel, err := s.Channel(voiceChannelID)
+	if err != nil {
+		return fmt.Errorf("failed to get voice channel: %w", err)
+	}
+	if !isPrivateVoiceChannel(channel) {
+		return nil
+	}
+
+	userIDs, err := getScheduledEventUserIDs(s, guild.ID, scheduledEvent.ID)
+	if err != nil {
+		return err
+	}
+
+	interested := map[string]bool{}
+	for _, userID := range userIDs {
+		interested[userID] = true
+
+		err = em.grantVoiceChannelAccess(ctx, s, event, channel, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, userID := range append([]string(nil), event.VoiceAccessIDs...) {
+		if !interested[userID] {
+			err = em.revokeVoiceAccess(ctx, s, event, userID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// A stage event went live, let the participants know in the event channel.
+func (em *EventManager) onStageInstanceCreate(ctx context.Context, log *logrus.Entry, s *discordgo.Session, m *discordgo.StageInstanceEventCreate) error {
+	if m.GuildScheduledEventID == "" {
+		return nil
+	}
+
+	_, event, err := em.getGuildAndEvent(ctx, m.GuildID, m.GuildScheduledEventID)
+	if err != nil {
+		return err
+	}
+
+	if event == nil || event.ChannelID == "" || event.Unlinked {
+		return nil
+	}
+
+	content := fmt.Sprintf("🎙️ The stage is live in <#%s>!", m.ChannelID)
+	if m.Topic != "" {
+		content = fmt.Sprintf("🎙️ The stage is live in <#%s>: **%s**", m.ChannelID, m.Topic)
+	}
+
+	_, err = s.ChannelMessageSend(event.ChannelID, content)
+	if err != nil {
+		return fmt.Errorf("failed to announce stage: %w", err)
+	}
+
+	return nil
+}
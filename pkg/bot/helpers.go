@@ -1,6 +1,8 @@
 package bot
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"regexp"
@@ -23,7 +25,7 @@ func eventChannelName(name string) string {
 
 func isDiscordErrRESTCode(err error, code int) bool {
 	restCode, present := getDiscordErrRESTCode(err)
-	if present {
+	if !present {
 		return false
 	}
 
@@ -43,6 +45,103 @@ func getDiscordErrRESTCode(err error) (int, bool) {
 	return discordErr.Response.StatusCode, true
 }
 
+func getAtEveryoneRole(s *discordgo.Session, guildID string) (*discordgo.Role, error) {
+	roles, err := s.GuildRoles(guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	for _, role := range roles {
+		if role.Name == "@everyone" {
+			return role, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to find @everyone role")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Adds the roleID to the list, or removes it, keeping the list free of duplicates.
+func toggleRoleID(roleIDs []string, roleID string, remove bool) []string {
+	toggled := make([]string, 0, len(roleIDs)+1)
+	for _, id := range roleIDs {
+		if id != roleID {
+			toggled = append(toggled, id)
+		}
+	}
+	if !remove {
+		toggled = append(toggled, roleID)
+	}
+
+	return toggled
+}
+
+// Finds a discordgo.GuildScheduledEvent by ID, falling back to a case-insensitive name match.
+func findScheduledEvent(s *discordgo.Session, guildID string, query string) (*discordgo.GuildScheduledEvent, error) {
+	events, err := s.GuildScheduledEvents(guildID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		if event.ID == query {
+			return event, nil
+		}
+	}
+
+	for _, event := range events {
+		if strings.EqualFold(event.Name, query) {
+			return event, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func getOptionsMap(optionsSlice []*discordgo.ApplicationCommandInteractionDataOption) map[string]*discordgo.ApplicationCommandInteractionDataOption {
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(optionsSlice))
+	for _, opt := range optionsSlice {
+		options[opt.Name] = opt
+	}
+
+	return options
+}
+
+func newSessionID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Discord rejects button labels longer than this.
+const maxButtonLabelLength = 80
+
+func truncateButtonLabel(label string) string {
+	runes := []rune(label)
+	if len(runes) <= maxButtonLabelLength {
+		return label
+	}
+
+	return string(runes[:maxButtonLabelLength-1]) + "…"
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
 const PGUniqueConstraintViolation = "23505"
 
 func isErrDuplicatePGConstraint(err error) bool {
@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	testGuildID = "100000000000000001"
+	testBotID   = "100000000000000002"
+)
+
+// handlerTransport answers the requests of a discordgo.Session with an http.Handler instead of Discord.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	t.handler.ServeHTTP(recorder, r)
+	return recorder.Result(), nil
+}
+
+// A discordgo.Session whose requests go to the handler, with the bot in a guild where @everyone has the
+// permissions given.
+func newTestSession(t *testing.T, handler http.HandlerFunc, everyonePermissions int64) *discordgo.Session {
+	t.Helper()
+
+	s, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Client = &http.Client{Transport: handlerTransport{handler: handler}}
+	s.State.User = &discordgo.User{ID: testBotID}
+
+	err = s.State.GuildAdd(&discordgo.Guild{
+		ID:      testGuildID,
+		OwnerID: "100000000000000003",
+		Roles:   []*discordgo.Role{{ID: testGuildID, Permissions: everyonePermissions}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.State.MemberAdd(&discordgo.Member{GuildID: testGuildID, User: &discordgo.User{ID: testBotID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func newTestEventManager() *EventManager {
+	return NewEventManager(nil, nil, EventManagerConfig{InteractionSecret: "secret"})
+}
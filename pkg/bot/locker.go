@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"sync"
+)
+
+// keyedMutex serializes work per key, such as the updates of a single Event, without one key blocking another.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{
+		locks: map[string]*keyedLock{},
+	}
+}
+
+// Blocks until the key is free and returns the function releasing it.
+func (m *keyedMutex) lock(key string) func() {
+	m.mu.Lock()
+	l, has := m.locks[key]
+	if !has {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		m.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,15 @@
+package bot
+
+import (
+	"time"
+)
+
+// CommandRegistration remembers what was last registered for an application and scope, so restarts don't
+// overwrite unchanged commands.
+type CommandRegistration struct {
+	// The application ID and the guild ID joined by a colon, the guild ID is empty for global commands.
+	ID   string `xorm:"pk"`
+	Hash string
+
+	UpdatedAt time.Time `xorm:"updated"`
+}
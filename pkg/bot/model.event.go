@@ -1,8 +1,43 @@
 package bot
 
+import (
+	"time"
+)
+
 type Event struct {
 	ID                string `xorm:"pk"`
 	GuildID           string
 	ChannelID         string
 	AnnounceMessageID *string
+
+	// Set when an admin unlinked the channel, so reconcile does not create a new one.
+	Unlinked bool
+
+	// Users the host delegated the moderation of the event channel to.
+	CohostIDs []string `xorm:"json"`
+
+	// Set when someone changed the name or the permissions of the channel and the Guild respects manual edits, so
+	// the bot stops managing them.
+	NameLocked        bool
+	PermissionsLocked bool
+
+	// The name the bot last gave the channel and the one Discord made of it, so a manual rename can be told apart
+	// from Discord's own normalization of the name.
+	SentChannelName string
+	ChannelName     string
+
+	// The invite to the channel, reused until it lapses before the event ends or is revoked along with the event.
+	// A nil InviteExpiresAt never lapses.
+	InviteCode      string
+	InviteExpiresAt *time.Time
+
+	// Users who joined the channel with the Join button, they keep access whether they are interested or not.
+	MemberIDs []string `xorm:"json"`
+
+	// The pinned message describing the event in its channel, edited as the event changes.
+	InfoMessageID string
+
+	// The voice or stage channel the bot let VoiceAccessIDs into, so only those grants are taken back.
+	VoiceChannelID string
+	VoiceAccessIDs []string `xorm:"json"`
 }
@@ -0,0 +1,24 @@
+package bot
+
+import (
+	"time"
+)
+
+// EventSetup records each step taken while creating the channel of an event, so a failed or interrupted setup
+// can be undone precisely or finished. It is removed once the Event is stored.
+type EventSetup struct {
+	// The ID of the discordgo.GuildScheduledEvent.
+	ID      string `xorm:"pk"`
+	GuildID string
+
+	ChannelID         string
+	SentChannelName   string
+	ChannelName       string
+	InviteCode        string
+	InviteExpiresAt   *time.Time
+	AnnounceChannelID string
+	AnnounceMessageID string
+	InfoMessageID     string
+
+	CreatedAt time.Time `xorm:"created"`
+}
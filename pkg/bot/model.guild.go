@@ -3,6 +3,20 @@ package bot
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type PermissionProfile = string
+
+const (
+	// Only the participants of the event can see its channel.
+	PermissionProfilePrivate PermissionProfile = "private"
+	// Everyone can see the channel of the event but only its participants can post.
+	PermissionProfilePublicReadOnly PermissionProfile = "public-read-only"
+	// Everyone can see and post in the channel of the event.
+	PermissionProfilePublic PermissionProfile = "public"
 )
 
 type Guild struct {
@@ -13,8 +27,43 @@ type Guild struct {
 	EventChannelParentID       string
 	ConfigurationWasRun        bool
 	FirstReconcileRun          bool
+	SetupStep                  string
+	// Categories created next to EventChannelParentID once it holds as many channels as Discord allows.
+	OverflowCategoryIDs []string `xorm:"json"`
+	// Members with this discordgo.Role may use the bot without the Manage Server permission.
+	ManagerRoleID string
+	// Members with any of these discordgo.Role get the host permissions in every event channel.
+	HostRoleIDs []string `xorm:"json"`
+	// Moderators with any of these discordgo.Role can always see and moderate every event channel.
+	StaffRoleIDs []string `xorm:"json"`
+	// Empty behaves like PermissionProfilePrivate.
+	PermissionProfile PermissionProfile
+	// Permission bits, limited to participantPermissions, granted or taken from the participants of every event.
+	ParticipantAllow int64
+	ParticipantDeny  int64
+	// The missing permissions last reported to the owner, so they are only told again when something changes.
+	PermissionProblems string
+
+	// Where the onboarding message was last delivered, the owner's direct messages or a fallback discordgo.Channel.
+	OnboardingChannelID string
+	LastNudgedAt        *time.Time
+	// How many reminders were sent while the configuration wasn't run, and whether the owner asked to stop them.
+	Nudges      int
+	NudgesMuted bool
 
-	// TODO: DM Server Owner on add to explain how to get started.
+	// What to do when someone deletes or edits an event channel, empty means ChannelDeletePolicyRecreate and
+	// ChannelEditPolicyRespect.
+	ChannelDeletePolicy ChannelDeletePolicy
+	ChannelEditPolicy   ChannelEditPolicy
+
+	// The topic of event channels, empty means defaultChannelTopicTemplate.
+	ChannelTopicTemplate string
+
+	// Lets interested users into the voice or stage channel of the event when @everyone can't join it.
+	VoiceAccess bool
+
+	// Set when the bot was removed, the Guild is purged once the retention window passed.
+	DeletedAt time.Time `xorm:"deleted"`
 }
 
 func (g *Guild) GetNewEventChannelMessage(eventName string, inviteCode string, eventID string) string {
@@ -23,3 +72,56 @@ func (g *Guild) GetNewEventChannelMessage(eventName string, inviteCode string, e
 		getEventInviteURL(inviteCode, eventID),
 	)
 }
+
+// Placeholders of the ChannelTopicTemplate, a line is left out when a placeholder on it has no value.
+const (
+	TopicPlaceholderEvent       = "%EVENT%"
+	TopicPlaceholderDescription = "%DESCRIPTION%"
+	TopicPlaceholderStart       = "%START%"
+	TopicPlaceholderLocation    = "%LOCATION%"
+)
+
+const defaultChannelTopicTemplate = "🗓️ " + TopicPlaceholderStart + "\n📍 " + TopicPlaceholderLocation + "\n" + TopicPlaceholderDescription
+
+// Discord refuses longer topics for text channels.
+const maxChannelTopicLength = 1024
+
+func (g *Guild) GetEventChannelTopic(scheduledEvent *discordgo.GuildScheduledEvent) string {
+	template := g.ChannelTopicTemplate
+	if template == "" {
+		template = defaultChannelTopicTemplate
+	}
+
+	values := map[string]string{
+		TopicPlaceholderEvent:       scheduledEvent.Name,
+		TopicPlaceholderDescription: scheduledEvent.Description,
+		TopicPlaceholderStart:       fmt.Sprintf("<t:%d:F>", scheduledEvent.ScheduledStartTime.Unix()),
+		TopicPlaceholderLocation:    getEventLocation(scheduledEvent),
+	}
+
+	var lines []string
+	for _, line := range strings.Split(template, "\n") {
+		skip := false
+		for placeholder, value := range values {
+			if !strings.Contains(line, placeholder) {
+				continue
+			}
+			if value == "" {
+				skip = true
+				break
+			}
+			line = strings.Replace(line, placeholder, value, -1)
+		}
+
+		if !skip {
+			lines = append(lines, line)
+		}
+	}
+
+	topic := strings.TrimSpace(strings.Join(lines, "\n"))
+	if runes := []rune(topic); len(runes) > maxChannelTopicLength {
+		topic = string(runes[:maxChannelTopicLength-1]) + "…"
+	}
+
+	return topic
+}
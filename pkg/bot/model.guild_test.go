@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestGetEventChannelTopic(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	external := func(location string, description string) *discordgo.GuildScheduledEvent {
+		return &discordgo.GuildScheduledEvent{
+			Name:               "Game Night",
+			Description:        description,
+			ScheduledStartTime: start,
+			EntityType:         discordgo.GuildScheduledEventEntityTypeExternal,
+			EntityMetadata:     discordgo.GuildScheduledEventEntityMetadata{Location: location},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		template       string
+		scheduledEvent *discordgo.GuildScheduledEvent
+		topic          string
+	}{
+		{"default template", "", external("The Pub", "Bring dice."), "🗓️ <t:1700000000:F>\n📍 The Pub\nBring dice."},
+		{"default template without location and description", "", external("", ""), "🗓️ <t:1700000000:F>"},
+		{
+			name:           "voice channel location",
+			template:       TopicPlaceholderLocation,
+			scheduledEvent: &discordgo.GuildScheduledEvent{EntityType: discordgo.GuildScheduledEventEntityTypeVoice, ChannelID: "600000000000000001"},
+			topic:          "<#600000000000000001>",
+		},
+		{"custom template", "Welcome to " + TopicPlaceholderEvent + "!\n" + TopicPlaceholderDescription, external("", "Bring dice."), "Welcome to Game Night!\nBring dice."},
+		{"line with an empty placeholder", TopicPlaceholderEvent + " at " + TopicPlaceholderLocation + "\nSee you there", external("", ""), "See you there"},
+		{"nothing to show", TopicPlaceholderDescription, external("", ""), ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			guild := &Guild{ChannelTopicTemplate: test.template}
+			if topic := guild.GetEventChannelTopic(test.scheduledEvent); topic != test.topic {
+				t.Errorf("got %q, want %q", topic, test.topic)
+			}
+		})
+	}
+
+	t.Run("too long", func(t *testing.T) {
+		guild := &Guild{ChannelTopicTemplate: TopicPlaceholderDescription}
+		topic := guild.GetEventChannelTopic(external("", strings.Repeat("é", 2*maxChannelTopicLength)))
+		if length := utf8.RuneCountInString(topic); length != maxChannelTopicLength {
+			t.Errorf("got %d characters, want %d", length, maxChannelTopicLength)
+		}
+		if !strings.HasSuffix(topic, "…") {
+			t.Errorf("got %q, want it to end with an ellipsis", topic[len(topic)-10:])
+		}
+	})
+}
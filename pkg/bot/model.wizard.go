@@ -0,0 +1,22 @@
+package bot
+
+import (
+	"time"
+)
+
+// WizardSession keeps the choices made in a sync wizard until Done is pressed, so paging through events or
+// searching channels doesn't lose them.
+type WizardSession struct {
+	ID      string `xorm:"pk"`
+	GuildID string
+	UserID  string
+	Page    int
+
+	// Event ID to the selected channel ID, an empty channel ID means a new channel will be created.
+	Selections map[string]string `xorm:"json"`
+	// Event ID to the search text used to filter its channel options.
+	Filters map[string]string `xorm:"json"`
+
+	CreatedAt time.Time `xorm:"created"`
+	UpdatedAt time.Time `xorm:"updated"`
+}
@@ -0,0 +1,313 @@
+package bot
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// What the host of an event, their co-hosts and the host roles may do in the event channel on top of participating.
+const eventHostPermissions = discordgo.PermissionViewChannel |
+	discordgo.PermissionManageMessages |
+	discordgo.PermissionManageThreads
+
+// What the staff roles get in every event channel, whether they take part in the event or not.
+const eventStaffPermissions = eventHostPermissions | participantPermissions | discordgo.PermissionReadMessageHistory
+
+// The permissions a Guild may allow or deny participants, and which @everyone loses in read-only event channels.
+const participantPermissions = discordgo.PermissionSendMessages |
+	discordgo.PermissionAttachFiles |
+	discordgo.PermissionAddReactions |
+	participantThreadPermissions
+
+const participantThreadPermissions = discordgo.PermissionCreatePublicThreads | discordgo.PermissionSendMessagesInThreads
+
+// What the bot needs in the event channels to post its messages regardless of the PermissionProfile.
+const eventBotPermissions = discordgo.PermissionViewChannel | discordgo.PermissionSendMessages | discordgo.PermissionEmbedLinks
+
+// The @everyone overwrite for the PermissionProfile of the Guild.
+func getAtEveryonePermissions(guild *Guild) (allow int64, deny int64) {
+	switch guild.PermissionProfile {
+	case PermissionProfilePublicReadOnly:
+		return discordgo.PermissionViewChannel, participantPermissions
+	case PermissionProfilePublic:
+		return discordgo.PermissionViewChannel, 0
+	default:
+		return 0, discordgo.PermissionViewChannel
+	}
+}
+
+// The overwrite for users interested in the event. In read-only channels they may post unless the Guild denies it.
+func getParticipantPermissions(guild *Guild) (allow int64, deny int64) {
+	allow = discordgo.PermissionViewChannel | guild.ParticipantAllow
+	if guild.PermissionProfile == PermissionProfilePublicReadOnly {
+		allow |= participantPermissions &^ guild.ParticipantDeny
+	}
+
+	return allow, guild.ParticipantDeny
+}
+
+// Hosts may always do what participants may do by default, whatever the Guild denies participants.
+func getHostPermissions(guild *Guild) int64 {
+	allow, _ := getParticipantPermissions(guild)
+	return allow | participantPermissions | eventHostPermissions
+}
+
+// overwriteSet collects discordgo.PermissionOverwrite entries, merging the bits given for the same role or member.
+type overwriteSet struct {
+	order      []string
+	overwrites map[string]*discordgo.PermissionOverwrite
+}
+
+func newOverwriteSet() *overwriteSet {
+	return &overwriteSet{
+		overwrites: map[string]*discordgo.PermissionOverwrite{},
+	}
+}
+
+func (o *overwriteSet) get(id string, overwriteType discordgo.PermissionOverwriteType) *discordgo.PermissionOverwrite {
+	overwrite, has := o.overwrites[id]
+	if !has {
+		overwrite = &discordgo.PermissionOverwrite{
+			ID:   id,
+			Type: overwriteType,
+		}
+		o.overwrites[id] = overwrite
+		o.order = append(o.order, id)
+	}
+
+	return overwrite
+}
+
+func (o *overwriteSet) allow(id string, overwriteType discordgo.PermissionOverwriteType, permissions int64) {
+	overwrite := o.get(id, overwriteType)
+	overwrite.Allow |= permissions
+	overwrite.Deny &^= permissions
+}
+
+func (o *overwriteSet) deny(id string, overwriteType discordgo.PermissionOverwriteType, permissions int64) {
+	overwrite := o.get(id, overwriteType)
+	overwrite.Deny |= permissions
+	overwrite.Allow &^= permissions
+}
+
+// Replaces the bits collected for the role or member.
+func (o *overwriteSet) set(id string, overwriteType discordgo.PermissionOverwriteType, allow int64, deny int64) {
+	overwrite := o.get(id, overwriteType)
+	overwrite.Allow = allow
+	overwrite.Deny = deny
+}
+
+func (o *overwriteSet) list() []*discordgo.PermissionOverwrite {
+	overwrites := make([]*discordgo.PermissionOverwrite, 0, len(o.order))
+	for _, id := range o.order {
+		overwrites = append(overwrites, o.overwrites[id])
+	}
+
+	return overwrites
+}
+
+// Builds the overwrites for an event channel following the PermissionProfile of the Guild: what @everyone may do, the
+// bot, every user marked as interested in the discordgo.GuildScheduledEvent or who joined with the button, and
+// moderation rights for the staff and the hosts. The Event is nil for a channel that is not tracked yet.
+func getEventPermissionOverwrites(s *discordgo.Session, guild *Guild, scheduledEvent *discordgo.GuildScheduledEvent, event *Event, atEveryoneRole *discordgo.Role) ([]*discordgo.PermissionOverwrite, error) {
+	var cohostIDs, memberIDs []string
+	if event != nil {
+		cohostIDs, memberIDs = event.CohostIDs, event.MemberIDs
+	}
+
+	overwrites := newOverwriteSet()
+	overwrites.allow(s.State.User.ID, discordgo.PermissionOverwriteTypeMember, eventBotPermissions)
+
+	atEveryoneAllow, atEveryoneDeny := getAtEveryonePermissions(guild)
+	overwrites.allow(atEveryoneRole.ID, discordgo.PermissionOverwriteTypeRole, atEveryoneAllow)
+	overwrites.deny(atEveryoneRole.ID, discordgo.PermissionOverwriteTypeRole, atEveryoneDeny)
+
+	participantAllow, participantDeny := getParticipantPermissions(guild)
+
+	var lastID string
+	for {
+		eventUsers, err := s.GuildScheduledEventUsers(guild.ID, scheduledEvent.ID, 100, false, "", lastID)
+		if err != nil {
+			return nil, err
+		}
+		if len(eventUsers) == 0 {
+			break
+		}
+		lastID = eventUsers[len(eventUsers)-1].User.ID
+		for _, eventUser := range eventUsers {
+			if eventUser.User.ID == s.State.User.ID {
+				continue
+			}
+
+			overwrites.allow(eventUser.User.ID, discordgo.PermissionOverwriteTypeMember, participantAllow)
+			overwrites.deny(eventUser.User.ID, discordgo.PermissionOverwriteTypeMember, participantDeny)
+		}
+	}
+
+	for _, userID := range memberIDs {
+		overwrites.allow(userID, discordgo.PermissionOverwriteTypeMember, participantAllow)
+		overwrites.deny(userID, discordgo.PermissionOverwriteTypeMember, participantDeny)
+	}
+
+	for _, roleID := range guild.StaffRoleIDs {
+		overwrites.allow(roleID, discordgo.PermissionOverwriteTypeRole, eventStaffPermissions)
+	}
+
+	hostAllow := getHostPermissions(guild)
+	for _, roleID := range guild.HostRoleIDs {
+		overwrites.allow(roleID, discordgo.PermissionOverwriteTypeRole, hostAllow)
+	}
+	for _, userID := range getEventHostIDs(scheduledEvent, cohostIDs) {
+		if userID == s.State.User.ID {
+			continue
+		}
+
+		// The same overwrite the Join button, co-host changes and onGuildEventUserAdd write for a host.
+		overwrites.set(userID, discordgo.PermissionOverwriteTypeMember, hostAllow, 0)
+	}
+
+	// Discord refuses overwrites handing out permissions the bot lacks, those are reported by the preflight instead.
+	grantable, err := getBotGrantablePermissions(s, guild)
+	if err != nil {
+		return nil, err
+	}
+
+	list := overwrites.list()
+	for _, overwrite := range list {
+		overwrite.Allow &= grantable
+		overwrite.Deny &= grantable
+	}
+
+	return list, nil
+}
+
+// The permissions the bot may allow or deny in event channels: those it has in their category, or in the server
+// when there is none.
+func getBotGrantablePermissions(s *discordgo.Session, guild *Guild) (int64, error) {
+	if guild.EventChannelParentID != "" {
+		return getBotChannelPermissions(s, guild.EventChannelParentID)
+	}
+
+	return getBotGuildPermissions(s, guild.ID)
+}
+
+// Sets the overwrite of a member in the event channel, leaving out the permissions the bot may not hand out so it
+// matches getEventPermissionOverwrites.
+func (em *EventManager) setEventMemberPermissions(s *discordgo.Session, guild *Guild, channelID string, userID string, allow int64, deny int64) error {
+	grantable, err := getBotGrantablePermissions(s, guild)
+	if err != nil {
+		return err
+	}
+
+	em.markOwnChannelEdit(channelID)
+	return s.ChannelPermissionSet(channelID, userID, discordgo.PermissionOverwriteTypeMember, allow&grantable, deny&grantable)
+}
+
+// The creator of the discordgo.GuildScheduledEvent followed by the co-hosts they added.
+func getEventHostIDs(scheduledEvent *discordgo.GuildScheduledEvent, cohostIDs []string) []string {
+	hostIDs := make([]string, 0, len(cohostIDs)+1)
+	if scheduledEvent != nil && scheduledEvent.CreatorID != "" {
+		hostIDs = append(hostIDs, scheduledEvent.CreatorID)
+	}
+
+	return append(hostIDs, cohostIDs...)
+}
+
+func isEventHost(scheduledEvent *discordgo.GuildScheduledEvent, cohostIDs []string, userID string) bool {
+	for _, hostID := range getEventHostIDs(scheduledEvent, cohostIDs) {
+		if hostID == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Whether the discordgo.User is marked as interested in the discordgo.GuildScheduledEvent.
+func isScheduledEventUser(s *discordgo.Session, guildID string, eventID string, userID string) (bool, error) {
+	var lastID string
+	for {
+		eventUsers, err := s.GuildScheduledEventUsers(guildID, eventID, 100, false, "", lastID)
+		if err != nil {
+			return false, err
+		}
+		if len(eventUsers) == 0 {
+			return false, nil
+		}
+		lastID = eventUsers[len(eventUsers)-1].User.ID
+		for _, eventUser := range eventUsers {
+			if eventUser.User.ID == userID {
+				return true, nil
+			}
+		}
+	}
+}
+
+// The users interested in the event, without the bot.
+func getScheduledEventUserIDs(s *discordgo.Session, guildID string, eventID string) ([]string, error) {
+	var userIDs []string
+	var lastID string
+	for {
+		eventUsers, err := s.GuildScheduledEventUsers(guildID, eventID, 100, false, "", lastID)
+		if err != nil {
+			return nil, err
+		}
+		if len(eventUsers) == 0 {
+			return userIDs, nil
+		}
+		lastID = eventUsers[len(eventUsers)-1].User.ID
+		for _, eventUser := range eventUsers {
+			if eventUser.User.ID != s.State.User.ID {
+				userIDs = append(userIDs, eventUser.User.ID)
+			}
+		}
+	}
+}
+
+// Whether both lists grant and deny the same permissions to the same roles and members, in any order.
+func equalPermissionOverwrites(a []*discordgo.PermissionOverwrite, b []*discordgo.PermissionOverwrite) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	byID := make(map[string]*discordgo.PermissionOverwrite, len(a))
+	for _, overwrite := range a {
+		byID[overwrite.ID] = overwrite
+	}
+	if len(byID) != len(a) {
+		return false
+	}
+
+	for _, overwrite := range b {
+		other, has := byID[overwrite.ID]
+		if !has || other.Type != overwrite.Type || other.Allow != overwrite.Allow || other.Deny != overwrite.Deny {
+			return false
+		}
+		delete(byID, overwrite.ID)
+	}
+
+	return true
+}
+
+// Makes the overwrites of the event discordgo.Channel match the settings of the Guild, skipping the edit when they
+// already do or when they were changed by hand and are locked.
+func (em *EventManager) syncEventChannelPermissions(s *discordgo.Session, guild *Guild, event *Event, scheduledEvent *discordgo.GuildScheduledEvent, channel *discordgo.Channel, atEveryoneRole *discordgo.Role) error {
+	if event.PermissionsLocked {
+		return nil
+	}
+
+	permissionOverwrites, err := getEventPermissionOverwrites(s, guild, scheduledEvent, event, atEveryoneRole)
+	if err != nil {
+		return err
+	}
+
+	if equalPermissionOverwrites(channel.PermissionOverwrites, permissionOverwrites) {
+		return nil
+	}
+
+	em.markOwnChannelEdit(channel.ID)
+	_, err = s.ChannelEditComplex(channel.ID, &discordgo.ChannelEdit{
+		PermissionOverwrites: permissionOverwrites,
+		Position:             channel.Position,
+	})
+	return err
+}
@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Answers the users of a scheduled event, on a single page.
+func scheduledEventUsersHandler(t *testing.T, userIDs ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		users := make([]*discordgo.GuildScheduledEventUser, 0, len(userIDs))
+		if r.URL.Query().Get("after") == "" {
+			for _, userID := range userIDs {
+				users = append(users, &discordgo.GuildScheduledEventUser{User: &discordgo.User{ID: userID}})
+			}
+		}
+
+		err := json.NewEncoder(w).Encode(users)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestGetEventPermissionOverwrites(t *testing.T) {
+	const (
+		interestedID = "200000000000000001"
+		memberID     = "200000000000000002"
+		creatorID    = "200000000000000003"
+		cohostID     = "200000000000000004"
+		staffRoleID  = "300000000000000001"
+		hostRoleID   = "300000000000000002"
+	)
+
+	everyone := &discordgo.Role{ID: testGuildID}
+	scheduledEvent := &discordgo.GuildScheduledEvent{ID: "400000000000000001", GuildID: testGuildID, CreatorID: creatorID}
+	event := &Event{ID: scheduledEvent.ID, MemberIDs: []string{memberID}, CohostIDs: []string{cohostID}}
+	required := int64(eventCategoryPermissions)
+
+	type overwrite struct{ allow, deny int64 }
+
+	tests := []struct {
+		name     string
+		guild    *Guild
+		event    *Event
+		bot      int64
+		expected map[string]overwrite
+	}{
+		{
+			name:  "private",
+			guild: &Guild{ID: testGuildID},
+			event: event,
+			bot:   allPermissions,
+			expected: map[string]overwrite{
+				testBotID:    {eventBotPermissions, 0},
+				testGuildID:  {0, discordgo.PermissionViewChannel},
+				interestedID: {discordgo.PermissionViewChannel, 0},
+				memberID:     {discordgo.PermissionViewChannel, 0},
+				creatorID:    {discordgo.PermissionViewChannel | participantPermissions | eventHostPermissions, 0},
+				cohostID:     {discordgo.PermissionViewChannel | participantPermissions | eventHostPermissions, 0},
+			},
+		},
+		{
+			name:  "public read-only with roles and a denied permission",
+			guild: &Guild{ID: testGuildID, PermissionProfile: PermissionProfilePublicReadOnly, ParticipantDeny: discordgo.PermissionAttachFiles, StaffRoleIDs: []string{staffRoleID}, HostRoleIDs: []string{hostRoleID}},
+			event: event,
+			bot:   allPermissions,
+			expected: map[string]overwrite{
+				testBotID:    {eventBotPermissions, 0},
+				testGuildID:  {discordgo.PermissionViewChannel, participantPermissions},
+				interestedID: {discordgo.PermissionViewChannel | participantPermissions&^discordgo.PermissionAttachFiles, discordgo.PermissionAttachFiles},
+				memberID:     {discordgo.PermissionViewChannel | participantPermissions&^discordgo.PermissionAttachFiles, discordgo.PermissionAttachFiles},
+				staffRoleID:  {eventStaffPermissions, 0},
+				hostRoleID:   {discordgo.PermissionViewChannel | participantPermissions | eventHostPermissions, 0},
+				// Hosts get exactly what the Join button and co-host changes write, whatever participants are denied.
+				creatorID: {discordgo.PermissionViewChannel | participantPermissions | eventHostPermissions, 0},
+				cohostID:  {discordgo.PermissionViewChannel | participantPermissions | eventHostPermissions, 0},
+			},
+		},
+		{
+			name:  "untracked channel",
+			guild: &Guild{ID: testGuildID, PermissionProfile: PermissionProfilePublic},
+			event: nil,
+			bot:   allPermissions,
+			expected: map[string]overwrite{
+				testBotID:    {eventBotPermissions, 0},
+				testGuildID:  {discordgo.PermissionViewChannel, 0},
+				interestedID: {discordgo.PermissionViewChannel, 0},
+				creatorID:    {discordgo.PermissionViewChannel | participantPermissions | eventHostPermissions, 0},
+			},
+		},
+		{
+			// discordgo.PermissionAll lacks the thread permissions, administrators still have them.
+			name:  "administrator bot",
+			guild: &Guild{ID: testGuildID, PermissionProfile: PermissionProfilePublic},
+			event: nil,
+			bot:   discordgo.PermissionAdministrator,
+			expected: map[string]overwrite{
+				testBotID:    {eventBotPermissions, 0},
+				testGuildID:  {discordgo.PermissionViewChannel, 0},
+				interestedID: {discordgo.PermissionViewChannel, 0},
+				creatorID:    {discordgo.PermissionViewChannel | participantPermissions | eventHostPermissions, 0},
+			},
+		},
+		{
+			name:  "bot without the staff permissions",
+			guild: &Guild{ID: testGuildID, PermissionProfile: PermissionProfilePublicReadOnly, StaffRoleIDs: []string{staffRoleID}},
+			event: event,
+			bot:   required,
+			expected: map[string]overwrite{
+				testBotID:    {eventBotPermissions, 0},
+				testGuildID:  {discordgo.PermissionViewChannel, participantPermissions & required},
+				interestedID: {(discordgo.PermissionViewChannel | participantPermissions) & required, 0},
+				memberID:     {(discordgo.PermissionViewChannel | participantPermissions) & required, 0},
+				staffRoleID:  {eventStaffPermissions & required, 0},
+				creatorID:    {(discordgo.PermissionViewChannel | participantPermissions | eventHostPermissions) & required, 0},
+				cohostID:     {(discordgo.PermissionViewChannel | participantPermissions | eventHostPermissions) & required, 0},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestSession(t, scheduledEventUsersHandler(t, interestedID, testBotID), test.bot)
+
+			overwrites, err := getEventPermissionOverwrites(s, test.guild, scheduledEvent, test.event, everyone)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(overwrites) != len(test.expected) {
+				t.Errorf("got %d overwrites, want %d", len(overwrites), len(test.expected))
+			}
+			for _, o := range overwrites {
+				expected, has := test.expected[o.ID]
+				if !has {
+					t.Errorf("unexpected overwrite for %s", o.ID)
+					continue
+				}
+				if o.Allow != expected.allow || o.Deny != expected.deny {
+					t.Errorf("overwrite for %s allows %b and denies %b, want %b and %b", o.ID, o.Allow, o.Deny, expected.allow, expected.deny)
+				}
+			}
+		})
+	}
+}
+
+func TestEqualPermissionOverwrites(t *testing.T) {
+	role := func(id string, allow int64, deny int64) *discordgo.PermissionOverwrite {
+		return &discordgo.PermissionOverwrite{ID: id, Type: discordgo.PermissionOverwriteTypeRole, Allow: allow, Deny: deny}
+	}
+	member := func(id string, allow int64, deny int64) *discordgo.PermissionOverwrite {
+		return &discordgo.PermissionOverwrite{ID: id, Type: discordgo.PermissionOverwriteTypeMember, Allow: allow, Deny: deny}
+	}
+
+	tests := []struct {
+		name  string
+		a     []*discordgo.PermissionOverwrite
+		b     []*discordgo.PermissionOverwrite
+		equal bool
+	}{
+		{"both empty", nil, []*discordgo.PermissionOverwrite{}, true},
+		{"same", []*discordgo.PermissionOverwrite{role("1", 1, 2), member("2", 4, 0)}, []*discordgo.PermissionOverwrite{role("1", 1, 2), member("2", 4, 0)}, true},
+		{"other order", []*discordgo.PermissionOverwrite{role("1", 1, 2), member("2", 4, 0)}, []*discordgo.PermissionOverwrite{member("2", 4, 0), role("1", 1, 2)}, true},
+		{"other allow", []*discordgo.PermissionOverwrite{role("1", 1, 2)}, []*discordgo.PermissionOverwrite{role("1", 3, 2)}, false},
+		{"other deny", []*discordgo.PermissionOverwrite{role("1", 1, 2)}, []*discordgo.PermissionOverwrite{role("1", 1, 0)}, false},
+		{"other type", []*discordgo.PermissionOverwrite{role("1", 1, 2)}, []*discordgo.PermissionOverwrite{member("1", 1, 2)}, false},
+		{"other id", []*discordgo.PermissionOverwrite{role("1", 1, 2)}, []*discordgo.PermissionOverwrite{role("2", 1, 2)}, false},
+		{"extra overwrite", []*discordgo.PermissionOverwrite{role("1", 1, 2)}, []*discordgo.PermissionOverwrite{role("1", 1, 2), member("2", 4, 0)}, false},
+		{"duplicate instead of another", []*discordgo.PermissionOverwrite{role("1", 1, 2), role("1", 1, 2)}, []*discordgo.PermissionOverwrite{role("1", 1, 2), member("2", 4, 0)}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if equal := equalPermissionOverwrites(test.a, test.b); equal != test.equal {
+				t.Errorf("equalPermissionOverwrites(a, b) = %v, want %v", equal, test.equal)
+			}
+			if equal := equalPermissionOverwrites(test.b, test.a); equal != test.equal {
+				t.Errorf("equalPermissionOverwrites(b, a) = %v, want %v", equal, test.equal)
+			}
+		})
+	}
+}